@@ -0,0 +1,202 @@
+//go:build go1.18
+// +build go1.18
+
+package itree
+
+import (
+	"net/netip"
+)
+
+// Addr implements Point for IP addresses, backed by netip.Addr. Addresses are
+// ordered like netip.Addr.Compare: all IPv4 addresses come before all IPv6
+// addresses, and addresses of the same family are ordered numerically.
+// IPv4-mapped IPv6 addresses are converted to IPv4 by AddrOf, so both forms of
+// an address compare equal.
+//
+// Each address family has an end point following its last address, so that
+// the range up to the last address, e. g., 0.0.0.0/0, can be represented as
+// the half-open interval [0.0.0.0,end). The zero value of Addr is not a valid
+// point.
+//
+// Addr requires Go 1.18 or later, which introduced net/netip.
+type Addr struct {
+	// ip is the address. If end is true, it is the last address of its family.
+	ip netip.Addr
+
+	// end indicates that this point lies right after ip.
+	end bool
+}
+
+// AddrOf returns the given valid address as Addr. IPv4-mapped IPv6 addresses
+// are converted to IPv4, and zones are removed.
+func AddrOf(ip netip.Addr) Addr {
+	if !ip.IsValid() {
+		panic("invalid address")
+	}
+	return Addr{
+		ip: ip.Unmap().WithZone(""),
+	}
+}
+
+// IP returns the address of this point. For an end point, the last address of
+// its family is returned.
+func (x Addr) IP() netip.Addr {
+	return x.ip
+}
+
+// IsEnd reports whether this point is the end point of its address family.
+func (x Addr) IsEnd() bool {
+	return x.end
+}
+
+// String returns the address of this point in its usual notation, or the
+// address followed by "+" for an end point.
+func (x Addr) String() string {
+	if x.end {
+		return x.ip.String() + "+"
+	}
+	return x.ip.String()
+}
+
+// Less checks whether x < y. It panics if y is not Addr.
+func (x Addr) Less(y Point) bool {
+	return x.Compare(y) < 0
+}
+
+// Compare implements Comparer. It panics if y is not Addr.
+func (x Addr) Compare(y Point) int {
+	ya := y.(Addr)
+	if c := x.ip.Compare(ya.ip); c != 0 {
+		return c
+	}
+	switch {
+	case x.end == ya.end:
+		return 0
+	case x.end:
+		return 1
+	}
+	return -1
+}
+
+// Succ implements Discrete. The successor of the last address of a family is
+// its end point, which has no successor.
+func (x Addr) Succ() (Point, bool) {
+	if x.end {
+		return nil, false
+	}
+	if next := x.ip.Next(); next.IsValid() {
+		return Addr{ip: next}, true
+	}
+	return Addr{ip: x.ip, end: true}, true
+}
+
+// Pred implements Discrete. The first address of a family has no predecessor.
+func (x Addr) Pred() (Point, bool) {
+	if x.end {
+		return Addr{ip: x.ip}, true
+	}
+	if prev := x.ip.Prev(); prev.IsValid() {
+		return Addr{ip: prev}, true
+	}
+	return nil, false
+}
+
+// lastAddr returns the last address of the given masked prefix.
+func lastAddr(p netip.Prefix) netip.Addr {
+	bytes := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(bytes)*8; i++ {
+		bytes[i/8] |= 0x80 >> (i % 8)
+	}
+	last, _ := netip.AddrFromSlice(bytes)
+	return last
+}
+
+// canonicalPrefix returns the given valid prefix masked, and converted to an
+// IPv4 prefix if it lies in the IPv4-mapped IPv6 address range.
+func canonicalPrefix(p netip.Prefix) netip.Prefix {
+	if !p.IsValid() {
+		panic("invalid prefix")
+	}
+	if p.Addr().Is4In6() && p.Bits() >= 96 {
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	return p.Masked()
+}
+
+// AddrPrefixInterval returns the interval of Addr points containing exactly
+// the addresses of the given prefix.
+func AddrPrefixInterval(p netip.Prefix) Interval {
+	p = canonicalPrefix(p)
+	end, _ := AddrOf(lastAddr(p)).Succ()
+	return Interval{
+		Left:  AddrOf(p.Addr()),
+		Right: end,
+	}
+}
+
+// IntervalPrefix returns the prefix containing exactly the addresses of the
+// given interval of Addr points, with ok == true. If there is no such prefix,
+// ok is false.
+func IntervalPrefix(iv Interval) (p netip.Prefix, ok bool) {
+	prefixes := RangePrefixes(iv)
+	if len(prefixes) != 1 {
+		return netip.Prefix{}, false
+	}
+	return prefixes[0], true
+}
+
+// RangePrefixes returns the minimal list of prefixes covering exactly the
+// addresses of the given interval of Addr points, in ascending order. The
+// interval must not contain addresses of both families.
+func RangePrefixes(iv Interval) []netip.Prefix {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	first := iv.Left.(Addr).ip
+	// An interval ending at the first IPv6 address ends with the IPv4 family.
+	last := lastAddr(netip.PrefixFrom(first, 0))
+	if pred, ok := iv.Right.(Addr).Pred(); ok {
+		last = pred.(Addr).ip
+	}
+	if first.BitLen() != last.BitLen() {
+		panic("mixed address families")
+	}
+	var prefixes []netip.Prefix
+	for {
+		// Find the largest block starting at first and ending at or before last.
+		var p netip.Prefix
+		for bits := 0; ; bits++ {
+			p = netip.PrefixFrom(first, bits).Masked()
+			if p.Addr() == first && lastAddr(p).Compare(last) <= 0 {
+				break
+			}
+		}
+		prefixes = append(prefixes, p)
+		end := lastAddr(p)
+		if end == last {
+			return prefixes
+		}
+		first = end.Next()
+	}
+}
+
+// LongestPrefixMatch returns the node with the longest prefix containing the
+// given address, i. e., the node with the smallest interval containing the
+// address, where the intervals of the nodes are prefixes as returned by
+// AddrPrefixInterval. If no node contains the address, nil is returned.
+// This operation runs in O(k+log(n)) time, where k is the number of nodes
+// containing the address and starting at the same address as the returned
+// node, and n is the size of this tree.
+func (t *T) LongestPrefixMatch(ip netip.Addr) *Node {
+	var best *Node
+	t.DescendContainingPoint(AddrOf(ip), func(n *Node) bool {
+		// Nested prefixes containing the address are visited by descending
+		// start address, and longer prefixes with the same start come later.
+		if best != nil && !equal(best.interval.Left, n.interval.Left) {
+			return false
+		}
+		best = n
+		return true
+	})
+	return best
+}
@@ -0,0 +1,135 @@
+//go:build go1.18
+// +build go1.18
+
+package itree
+
+import (
+	"math/rand"
+	"net/netip"
+	"testing"
+)
+
+// TestAddr tests ordering and conversion of Addr points.
+func TestAddr(t *testing.T) {
+	mapped := AddrOf(netip.MustParseAddr("::ffff:10.1.2.3"))
+	if !equal(mapped, AddrOf(netip.MustParseAddr("10.1.2.3"))) {
+		t.Errorf("IPv4-mapped address %v not equal to IPv4 address", mapped)
+	}
+	if !AddrOf(netip.MustParseAddr("255.255.255.255")).Less(
+		AddrOf(netip.MustParseAddr("::"))) {
+		t.Error("IPv4 address not before IPv6 address")
+	}
+	all4 := AddrPrefixInterval(netip.MustParsePrefix("0.0.0.0/0"))
+	if end := all4.Right.(Addr); !end.IsEnd() || !all4.Right.Less(
+		AddrOf(netip.MustParseAddr("::"))) {
+		t.Errorf("unexpected end point %v", end)
+	}
+	if _, ok := all4.Right.(Addr).Succ(); ok {
+		t.Error("end point has a successor")
+	}
+	iv := AddrPrefixInterval(netip.MustParsePrefix("10.1.2.3/16"))
+	expected := Interval{AddrOf(netip.MustParseAddr("10.1.0.0")),
+		AddrOf(netip.MustParseAddr("10.2.0.0"))}
+	if !iv.Equal(expected) {
+		t.Errorf("expected interval %v, got %v", expected, iv)
+	}
+	iv = AddrPrefixInterval(netip.MustParsePrefix("::ffff:10.1.0.0/112"))
+	if !iv.Equal(expected) {
+		t.Errorf("expected mapped prefix interval %v, got %v", expected, iv)
+	}
+	for _, s := range []string{
+		"10.1.0.0/16", "0.0.0.0/0", "::/0", "2001:db8::/33",
+	} {
+		p := netip.MustParsePrefix(s)
+		if q, ok := IntervalPrefix(AddrPrefixInterval(p)); !ok || q != p {
+			t.Errorf("expected prefix %v, got %v", p, q)
+		}
+	}
+	if _, ok := IntervalPrefix(Interval{AddrOf(netip.MustParseAddr("10.0.0.1")),
+		AddrOf(netip.MustParseAddr("10.0.0.3"))}); ok {
+		t.Error("unexpected prefix for [10.0.0.1,10.0.0.3)")
+	}
+}
+
+// checkPrefixes checks that RangePrefixes returns the expected prefixes for
+// the given interval.
+func checkPrefixes(t *testing.T, iv Interval, expected ...string) {
+	prefixes := RangePrefixes(iv)
+	if len(prefixes) != len(expected) {
+		t.Errorf("%v: expected prefixes %v, got %v", iv, expected, prefixes)
+		return
+	}
+	for i, p := range prefixes {
+		if p.String() != expected[i] {
+			t.Errorf("%v: expected prefix %s at %d, got %v", iv, expected[i], i, p)
+		}
+	}
+}
+
+// TestRangePrefixes tests decomposing address ranges into prefixes.
+func TestRangePrefixes(t *testing.T) {
+	checkPrefixes(t, Interval{AddrOf(netip.MustParseAddr("10.0.0.1")),
+		AddrOf(netip.MustParseAddr("10.0.0.9"))},
+		"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/30", "10.0.0.8/32")
+	ip4 := AddrOf(netip.MustParseAddr("10.0.0.0"))
+	checkPrefixes(t, Interval{ip4, AddrOf(netip.IPv6Unspecified())},
+		"10.0.0.0/7", "12.0.0.0/6", "16.0.0.0/4", "32.0.0.0/3", "64.0.0.0/2",
+		"128.0.0.0/1")
+	expectPanic(t, "mixed address families", func() {
+		RangePrefixes(Interval{ip4, AddrOf(netip.MustParseAddr("::1"))})
+	})
+	seedOnce.Do(seedRand)
+	for i := 0; i != 100; i++ {
+		lo := rand.Uint32()
+		hi := lo + 1 + uint32(rand.Intn(1<<20))
+		if hi < lo {
+			continue
+		}
+		iv := Interval{addr4(lo), addr4(hi)}
+		next := iv.Left
+		for _, p := range RangePrefixes(iv) {
+			piv := AddrPrefixInterval(p)
+			if !equal(piv.Left, next) {
+				t.Errorf("%v: prefix %v does not start at %v", iv, p, next)
+			}
+			next = piv.Right
+		}
+		if !equal(next, iv.Right) {
+			t.Errorf("%v: prefixes end at %v", iv, next)
+		}
+	}
+}
+
+// addr4 returns the IPv4 address with the given numeric value as Addr.
+func addr4(x uint32) Addr {
+	return AddrOf(netip.AddrFrom4([4]byte{
+		byte(x >> 24), byte(x >> 16), byte(x >> 8), byte(x),
+	}))
+}
+
+// TestLongestPrefixMatch tests longest-prefix-match lookups.
+func TestLongestPrefixMatch(t *testing.T) {
+	var tree T
+	for _, s := range []string{"0.0.0.0/0", "10.0.0.0/8", "10.1.0.0/16",
+		"10.1.2.0/24", "10.1.4.0/24"} {
+		tree.ReplaceOrInsert(AddrPrefixInterval(netip.MustParsePrefix(s)), s)
+	}
+	for _, c := range []struct {
+		ip, prefix string
+	}{
+		{"10.1.2.3", "10.1.2.0/24"},
+		{"10.1.3.3", "10.1.0.0/16"},
+		{"10.0.0.0", "10.0.0.0/8"},
+		{"::ffff:10.1.4.255", "10.1.4.0/24"},
+		{"8.8.8.8", "0.0.0.0/0"},
+	} {
+		n := tree.LongestPrefixMatch(netip.MustParseAddr(c.ip))
+		if n == nil || n.Value != c.prefix {
+			t.Errorf("%s: expected prefix %s, got %v", c.ip, c.prefix, n)
+		}
+	}
+	ip6 := netip.MustParseAddr("2001:db8::1")
+	if n := tree.LongestPrefixMatch(ip6); n != nil {
+		t.Errorf("unexpected match %v for IPv6 address", n.Value)
+	}
+}
@@ -0,0 +1,141 @@
+package itree
+
+// Monoid defines user-defined subtree aggregates over the nodes of a tree.
+// Aggregates are combined in the tree sort-order (see Interval.Less), so
+// Combine need not be commutative.
+type Monoid interface {
+	// Identity returns the identity element of this monoid, i. e., the
+	// aggregate of no nodes. For all aggregates x,
+	// Combine(Identity(), x) and Combine(x, Identity()) must equal x.
+	Identity() interface{}
+
+	// Combine returns the aggregate of the nodes aggregated in x, followed by
+	// the nodes aggregated in y. Combine must be associative and must not
+	// modify x or y.
+	Combine(x, y interface{}) interface{}
+
+	// FromNode returns the aggregate of the given single node. The result may
+	// depend only on the node interval and value.
+	FromNode(n *Node) interface{}
+}
+
+// NewWithMonoid creates a new, empty interval tree maintaining subtree
+// aggregates as defined by the given monoid. The aggregates are kept up to
+// date by all tree operations, so aggregate queries such as AggregateRange
+// run in O(log(n)) time. Node values in such a tree must be changed with
+// SetValue.
+func NewWithMonoid(m Monoid) *T {
+	return &T{
+		monoid: m,
+	}
+}
+
+// SetValue sets the value of the given node, which must be part of this tree,
+// and updates the subtree aggregates of the node and its ancestors.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) SetValue(n *Node, value interface{}) {
+	n.flush()
+	n.Value = value
+	if t.monoid != nil {
+		t.updateAncestors(n)
+	}
+}
+
+// Aggregate returns the aggregate of all nodes in this tree.
+// Aggregate panics if the tree has not been created with NewWithMonoid.
+func (t *T) Aggregate() interface{} {
+	return t.AggregateRange(nil, nil)
+}
+
+// AggregateRange returns the aggregate of all nodes whose left endpoint lies
+// in [lo,hi). If lo or hi is nil, the range is unbounded on that side.
+// AggregateRange panics if the tree has not been created with NewWithMonoid.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) AggregateRange(lo, hi Point) interface{} {
+	if t.monoid == nil {
+		panic("no monoid")
+	}
+	return t.root.aggregateRange(t.monoid, lo, hi)
+}
+
+// AggregateOverlapping returns the aggregate of all nodes overlapping with the
+// given interval. AggregateOverlapping panics if the tree has not been
+// created with NewWithMonoid.
+// Subtrees all of whose nodes overlap with iv contribute their precomputed
+// aggregate, so this operation runs in O((k+1)·log(n)) time, where k is the
+// number of nodes starting before iv.Right but ending at or before iv.Left,
+// and n is the size of this tree.
+func (t *T) AggregateOverlapping(iv Interval) interface{} {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	if t.monoid == nil {
+		panic("no monoid")
+	}
+	return t.root.aggregateOverlapping(t.monoid, iv, false)
+}
+
+// LazyMonoid is a Monoid which additionally supports lazily propagated range
+// updates of node values (see UpdateRange). Updating a node value v with an
+// update delta yields ApplyValue(v, delta). FromNode must be compatible with
+// this, i. e., after the update, FromNode must yield
+// ApplyAggregate(x, delta, 1), where x is the result of FromNode before the
+// update.
+type LazyMonoid interface {
+	Monoid
+
+	// ApplyValue returns the given node value with the given update applied.
+	// ApplyValue must not modify value.
+	ApplyValue(value, delta interface{}) interface{}
+
+	// ApplyAggregate returns the aggregate of size nodes previously aggregated
+	// in agg, after the given update has been applied to each of them.
+	// ApplyAggregate must not modify agg.
+	ApplyAggregate(agg, delta interface{}, size int) interface{}
+
+	// Compose returns an update equivalent to applying first, then second.
+	Compose(first, second interface{}) interface{}
+}
+
+// lazyMonoid returns the monoid of this tree as a LazyMonoid.
+// It panics if the tree has no monoid or the monoid is not a LazyMonoid.
+func (t *T) lazyMonoid() LazyMonoid {
+	if m, ok := t.monoid.(LazyMonoid); ok {
+		return m
+	}
+	panic("no lazy monoid")
+}
+
+// UpdateRange applies the given update to the values of all nodes whose left
+// endpoint lies in [lo,hi). If lo or hi is nil, the range is unbounded on that
+// side. UpdateRange panics if the tree has not been created with
+// NewWithMonoid, using a LazyMonoid.
+// Updates of whole subtrees are recorded in the subtree root and propagated to
+// the descendants only when they are accessed, so this operation runs in
+// O(log(n)) time, where n is the size of this tree.
+func (t *T) UpdateRange(lo, hi Point, delta interface{}) {
+	t.root.updateRange(t, t.lazyMonoid(), lo, hi, delta)
+}
+
+// UpdateOverlapping applies the given update to the values of all nodes
+// overlapping with the given interval. UpdateOverlapping panics if the tree
+// has not been created with NewWithMonoid, using a LazyMonoid.
+// Updates of subtrees all of whose nodes overlap with iv are recorded in the
+// subtree root and propagated to the descendants only when they are accessed,
+// so this operation has the same complexity as AggregateOverlapping.
+func (t *T) UpdateOverlapping(iv Interval, delta interface{}) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	t.root.updateOverlapping(t, t.lazyMonoid(), iv, false, delta)
+}
+
+// Flush applies all pending lazy updates (see UpdateRange and Shift) to the
+// value and interval of the given node, which must be part of this tree.
+// Nodes obtained through any lookup, query or iteration have no pending
+// updates, so Flush is only needed for nodes obtained before the most recent
+// UpdateRange, UpdateOverlapping or Shift.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) Flush(n *Node) {
+	n.flush()
+}
@@ -0,0 +1,229 @@
+package itree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// listMonoid is a non-commutative monoid concatenating node values.
+type listMonoid struct{}
+
+// Identity implements Monoid.Identity.
+func (listMonoid) Identity() interface{} {
+	return []interface{}(nil)
+}
+
+// Combine implements Monoid.Combine.
+func (listMonoid) Combine(x, y interface{}) interface{} {
+	xl, yl := x.([]interface{}), y.([]interface{})
+	result := make([]interface{}, 0, len(xl)+len(yl))
+	return append(append(result, xl...), yl...)
+}
+
+// FromNode implements Monoid.FromNode.
+func (listMonoid) FromNode(n *Node) interface{} {
+	return []interface{}{n.Value}
+}
+
+// testAggregates tests the subtree aggregates of the given tree, which must
+// have been created with a listMonoid.
+func testAggregates(t *testing.T, tree *T) {
+	testInvariants(t, tree)
+	var walk func(n *Node) []interface{}
+	walk = func(n *Node) []interface{} {
+		if n == nil {
+			return nil
+		}
+		list := append(walk(n.left), n.Value)
+		list = append(list, walk(n.right)...)
+		if !reflect.DeepEqual(list, n.agg) {
+			t.Errorf("node %v expected aggregate %v, have %v", n.Value, list, n.agg)
+		}
+		return list
+	}
+	walk(tree.root)
+}
+
+// TestAggregate tests subtree aggregates with random inserts and deletes.
+func TestAggregate(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	expectPanic(t, "Aggregate without monoid", func() {
+		tree.Aggregate()
+	})
+	mtree := NewWithMonoid(listMonoid{})
+	if agg := mtree.Aggregate().([]interface{}); len(agg) != 0 {
+		t.Errorf("non-empty aggregate %v of empty tree", agg)
+	}
+	for i := 0; i != 1000; i++ {
+		iv := randomInterval()
+		mtree.ReplaceOrInsert(iv, i)
+		if rand.Intn(3) == 0 {
+			mtree.DeleteNode(mtree.Sample(rand.New(rand.NewSource(int64(i)))))
+		}
+		if i%10 == 0 {
+			mtree.ReplaceOrInsert(iv, -i)
+		}
+	}
+	mtree.SetValue(mtree.GetMax(), "max")
+	testAggregates(t, mtree)
+	for i := 0; i != 100; i++ {
+		iv := randomInterval()
+		var inRange, overlapping []interface{}
+		for n := mtree.GetMin(); n != nil; n = n.Next() {
+			if lessOrEqual(iv.Left, n.interval.Left) &&
+				n.interval.Left.Less(iv.Right) {
+				inRange = append(inRange, n.Value)
+			}
+			if n.interval.Overlaps(iv) {
+				overlapping = append(overlapping, n.Value)
+			}
+		}
+		agg := mtree.AggregateRange(iv.Left, iv.Right).([]interface{})
+		if len(agg) != 0 || len(inRange) != 0 {
+			if !reflect.DeepEqual(agg, inRange) {
+				t.Errorf("AggregateRange(%v): expected %v, got %v", iv, inRange, agg)
+			}
+		}
+		agg = mtree.AggregateOverlapping(iv).([]interface{})
+		if len(agg) != 0 || len(overlapping) != 0 {
+			if !reflect.DeepEqual(agg, overlapping) {
+				t.Errorf("AggregateOverlapping(%v): expected %v, got %v",
+					iv, overlapping, agg)
+			}
+		}
+	}
+	agg := mtree.AggregateRange(nil, nil).([]interface{})
+	if len(agg) != mtree.Len() {
+		t.Errorf("expected aggregate of length %d, got %d", mtree.Len(), len(agg))
+	}
+}
+
+// affine is an update mapping an integer value x to a*x+b.
+type affine struct {
+	a, b int
+}
+
+// sumMonoid is a LazyMonoid summing integer node values, supporting affine
+// updates.
+type sumMonoid struct{}
+
+// Identity implements Monoid.Identity.
+func (sumMonoid) Identity() interface{} {
+	return 0
+}
+
+// Combine implements Monoid.Combine.
+func (sumMonoid) Combine(x, y interface{}) interface{} {
+	return x.(int) + y.(int)
+}
+
+// FromNode implements Monoid.FromNode.
+func (sumMonoid) FromNode(n *Node) interface{} {
+	return n.Value
+}
+
+// ApplyValue implements LazyMonoid.ApplyValue.
+func (sumMonoid) ApplyValue(value, delta interface{}) interface{} {
+	f := delta.(affine)
+	return f.a*value.(int) + f.b
+}
+
+// ApplyAggregate implements LazyMonoid.ApplyAggregate.
+func (sumMonoid) ApplyAggregate(agg, delta interface{}, size int) interface{} {
+	f := delta.(affine)
+	return f.a*agg.(int) + f.b*size
+}
+
+// Compose implements LazyMonoid.Compose.
+func (sumMonoid) Compose(first, second interface{}) interface{} {
+	f, g := first.(affine), second.(affine)
+	return affine{g.a * f.a, g.a*f.b + g.b}
+}
+
+// TestLazyUpdate tests lazy range updates with random operations.
+func TestLazyUpdate(t *testing.T) {
+	seedOnce.Do(seedRand)
+	expectPanic(t, "UpdateRange without lazy monoid", func() {
+		NewWithMonoid(listMonoid{}).UpdateRange(nil, nil, affine{1, 1})
+	})
+	tree := NewWithMonoid(sumMonoid{})
+	checkMap := make(map[Interval]int)
+	check := func() {
+		count, sum := 0, 0
+		for _, value := range checkMap {
+			sum += value
+		}
+		if agg := tree.Aggregate(); agg != sum {
+			t.Errorf("expected aggregate %d, got %v", sum, agg)
+		}
+		for n := tree.GetMin(); n != nil; n = n.Next() {
+			if n.Value != checkMap[n.interval] {
+				t.Errorf("node %v expected value %d, have %v",
+					n.interval, checkMap[n.interval], n.Value)
+			}
+			count++
+		}
+		if count != len(checkMap) {
+			t.Errorf("expected %d nodes, got %d", len(checkMap), count)
+		}
+		testInvariants(t, tree)
+	}
+	for i := 0; i != 2000; i++ {
+		iv := randomInterval()
+		f := affine{rand.Intn(3) - 1, rand.Intn(10)}
+		switch rand.Intn(6) {
+		case 0:
+			tree.ReplaceOrInsert(iv, i)
+			checkMap[iv] = i
+		case 1:
+			if n := tree.Sample(rand.New(rand.NewSource(int64(i)))); n != nil {
+				delete(checkMap, n.interval)
+				tree.DeleteNode(n)
+			}
+		case 2:
+			tree.UpdateRange(iv.Left, iv.Right, f)
+			for key, value := range checkMap {
+				if lessOrEqual(iv.Left, key.Left) && key.Left.Less(iv.Right) {
+					checkMap[key] = f.a*value + f.b
+				}
+			}
+		case 3:
+			tree.UpdateOverlapping(iv, f)
+			for key, value := range checkMap {
+				if key.Overlaps(iv) {
+					checkMap[key] = f.a*value + f.b
+				}
+			}
+		case 4:
+			sum := 0
+			for key, value := range checkMap {
+				if key.Overlaps(iv) {
+					sum += value
+				}
+			}
+			if agg := tree.AggregateOverlapping(iv); agg != sum {
+				t.Errorf("AggregateOverlapping(%v): expected %d, got %v",
+					iv, sum, agg)
+			}
+		case 5:
+			tree.ReplaceOrInsert(iv, i)
+			checkMap[iv] = i
+			n := tree.GetNode(iv)
+			tree.UpdateRange(nil, nil, f)
+			tree.Flush(n)
+			if n.Value != f.a*i+f.b {
+				t.Errorf("flushed node %v expected value %d, have %v",
+					iv, f.a*i+f.b, n.Value)
+			}
+			for key, value := range checkMap {
+				checkMap[key] = f.a*value + f.b
+			}
+		}
+		if i%100 == 0 {
+			check()
+		}
+	}
+	check()
+}
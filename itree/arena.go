@@ -0,0 +1,846 @@
+package itree
+
+// ArenaTree is an interval tree variant whose nodes live in a contiguous
+// slice and refer to each other by 32-bit indices instead of pointers. This
+// replaces the heap object per node with a single slice and improves cache
+// locality for very large trees. Slots of deleted nodes are kept on a free
+// list and reused by later insertions; Compact renumbers the nodes in
+// sort-order for sequential scans. The intervals, values and maxRight
+// endpoints are still stored as interface values, so the garbage collector
+// still has to scan the node slice, just not follow pointers between nodes.
+//
+// ArenaTree implements the red-black logic of T on indices. It offers exactly
+// the following: insertion, lookup and deletion by interval (ReplaceOrInsert,
+// Get and Delete), ordered access by interval (GetMin, GetMax, Next, Previous,
+// Ascend and Descend), the four interval queries of T, both as sorted lists
+// and as descending traversals (ContainingPoint, DescendContainingPoint, etc.),
+// NextStart and NextEnd, and Compact. There is no access to individual nodes,
+// and no weights, monoids, markers, lazy updates, splitting, joining, bulk
+// deletions or end index (see T.SetEndIndex), as a pointer-based index would
+// bring back the heap objects this variant avoids. The zero value of
+// ArenaTree is an empty tree.
+type ArenaTree struct {
+	// nodes contains the nodes of this tree. The node at index 0 is unused, so
+	// that index 0 can serve as nil index.
+	nodes []arenaNode
+
+	// root is the index of the root node, or 0 if this tree is empty.
+	root uint32
+
+	// free is the index of the first free slot in nodes, or 0 if there is none.
+	// The free slots are linked through their right field.
+	free uint32
+
+	// length is the number of nodes in this tree.
+	length int
+}
+
+// arenaNode is a node of an ArenaTree.
+type arenaNode struct {
+	// value is the node value.
+	value interface{}
+
+	// interval is the interval which is mapped to value by this node.
+	interval Interval
+
+	// maxRight is the maximal right endpoint in the subtree defined by this node.
+	maxRight Point
+
+	// parent, left, and right are the indices of the parent node and the left
+	// and right child, respectively, of this node, or 0 if there is no such
+	// node.
+	parent, left, right uint32
+
+	// red indicates whether this node is red in the red-black tree structure.
+	red bool
+}
+
+// maxArenaNodes is the maximal number of slots in the nodes of an ArenaTree.
+const maxArenaNodes = 1<<32 - 1
+
+// NewArenaTree creates a new, empty arena tree with room for the given number
+// of nodes before its node slice has to grow.
+func NewArenaTree(capacity int) *ArenaTree {
+	return &ArenaTree{
+		nodes: make([]arenaNode, 1, capacity+1),
+	}
+}
+
+// Len returns the number of elements in this tree.
+func (a *ArenaTree) Len() int {
+	return a.length
+}
+
+// alloc stores a new red node with the given interval and value in a free
+// slot, and returns its index.
+func (a *ArenaTree) alloc(iv Interval, value interface{}) uint32 {
+	if len(a.nodes) == 0 {
+		a.nodes = append(a.nodes, arenaNode{})
+	}
+	i := a.free
+	if i != 0 {
+		a.free = a.nodes[i].right
+	} else {
+		if uint64(len(a.nodes)) == maxArenaNodes {
+			panic("arena full")
+		}
+		i = uint32(len(a.nodes))
+		a.nodes = append(a.nodes, arenaNode{})
+	}
+	a.nodes[i] = arenaNode{
+		value:    value,
+		interval: iv,
+		maxRight: iv.Right,
+		red:      true,
+	}
+	return i
+}
+
+// release adds the slot with the given index to the free list.
+func (a *ArenaTree) release(i uint32) {
+	a.nodes[i] = arenaNode{
+		right: a.free,
+	}
+	a.free = i
+}
+
+// black reports whether the node with the given index is black. The nil index
+// denotes a black node.
+func (a *ArenaTree) black(i uint32) bool {
+	return i == 0 || !a.nodes[i].red
+}
+
+// sibling returns the index of the sibling of the node with index i, whose
+// parent has the given index.
+func (a *ArenaTree) sibling(i, parent uint32) uint32 {
+	switch {
+	case parent == 0:
+		return 0
+	case a.nodes[parent].left == i:
+		return a.nodes[parent].right
+	default:
+		return a.nodes[parent].left
+	}
+}
+
+// update recomputes the maxRight value of the node with the given index from
+// its interval and children.
+func (a *ArenaTree) update(i uint32) {
+	n := &a.nodes[i]
+	n.maxRight = n.interval.Right
+	for _, child := range [...]uint32{n.left, n.right} {
+		if child != 0 && n.maxRight.Less(a.nodes[child].maxRight) {
+			n.maxRight = a.nodes[child].maxRight
+		}
+	}
+}
+
+// updateAncestors calls update on the node with the given index and all its
+// ancestors.
+func (a *ArenaTree) updateAncestors(i uint32) {
+	for ; i != 0; i = a.nodes[i].parent {
+		a.update(i)
+	}
+}
+
+// replaceChild makes the node with index to a child of parent in place of the
+// node with index from. If parent is 0, to becomes the root node.
+func (a *ArenaTree) replaceChild(parent, from, to uint32) {
+	switch {
+	case parent == 0:
+		a.root = to
+	case a.nodes[parent].left == from:
+		a.nodes[parent].left = to
+	default:
+		a.nodes[parent].right = to
+	}
+	if to != 0 {
+		a.nodes[to].parent = parent
+	}
+}
+
+// rotateLeft performs a left rotation of the node with index m, which must
+// have a right child, like T.rotateLeft.
+func (a *ArenaTree) rotateLeft(m uint32) {
+	n := a.nodes[m].right
+	y := a.nodes[n].left
+	a.replaceChild(a.nodes[m].parent, m, n)
+	a.nodes[n].left = m
+	a.nodes[m].parent = n
+	a.nodes[m].right = y
+	if y != 0 {
+		a.nodes[y].parent = m
+	}
+	a.update(m)
+	a.update(n)
+}
+
+// rotateRight performs a right rotation of the node with index n, which must
+// have a left child, like T.rotateRight.
+func (a *ArenaTree) rotateRight(n uint32) {
+	m := a.nodes[n].left
+	y := a.nodes[m].right
+	a.replaceChild(a.nodes[n].parent, n, m)
+	a.nodes[m].right = n
+	a.nodes[n].parent = m
+	a.nodes[n].left = y
+	if y != 0 {
+		a.nodes[y].parent = n
+	}
+	a.update(n)
+	a.update(m)
+}
+
+// rebalanceRed rebalances the tree for the case that the node with index n is
+// red and has a red parent, like T.rebalanceRed.
+func (a *ArenaTree) rebalanceRed(n uint32) {
+	for !a.black(n) {
+		parent := a.nodes[n].parent
+		if parent == 0 {
+			a.nodes[n].red = false
+			return
+		}
+		if !a.nodes[parent].red {
+			return
+		}
+		grandparent := a.nodes[parent].parent // non-zero since parent is red
+		auncle := a.sibling(parent, grandparent)
+		if !a.black(auncle) {
+			// Red auncle: repaint and continue with the grandparent.
+			a.nodes[auncle].red = false
+			a.nodes[parent].red = false
+			a.nodes[grandparent].red = true
+			n = grandparent
+			continue
+		}
+		// Black auncle: rotate such that the grandparent becomes n's red
+		// sibling, and n's parent becomes black.
+		switch {
+		case n == a.nodes[parent].right && parent == a.nodes[grandparent].left:
+			a.rotateLeft(parent)
+			n, parent = parent, n
+		case n == a.nodes[parent].left && parent == a.nodes[grandparent].right:
+			a.rotateRight(parent)
+			n, parent = parent, n
+		}
+		a.nodes[parent].red = false
+		a.nodes[grandparent].red = true
+		if n == a.nodes[parent].left {
+			a.rotateRight(grandparent)
+		} else {
+			a.rotateLeft(grandparent)
+		}
+		return
+	}
+}
+
+// rebalanceBlack rebalances the tree after a black node with a black parent
+// was removed, like T.rebalanceBlack. The node with index n is the new child of
+// parent (n may be 0, so parent must also be given).
+func (a *ArenaTree) rebalanceBlack(parent, n uint32) {
+	for parent != 0 {
+		sibling := a.sibling(n, parent) // non-zero because removed node was black
+		if a.nodes[sibling].red {
+			a.nodes[parent].red = true
+			a.nodes[sibling].red = false
+			if n == a.nodes[parent].left {
+				a.rotateLeft(parent)
+			} else {
+				a.rotateRight(parent)
+			}
+			sibling = a.sibling(n, parent)
+		}
+		lniecew, rniecew := a.nodes[sibling].left, a.nodes[sibling].right
+		if a.black(lniecew) && a.black(rniecew) {
+			// Case 1: sibling and niecews are black: fix parent recursively.
+			a.nodes[sibling].red = true
+			if a.nodes[parent].red {
+				a.nodes[parent].red = false
+				return
+			}
+			n, parent = parent, a.nodes[parent].parent
+			continue
+		}
+		// Case 2: rotate a red niecew up, reducing to case 3.
+		switch {
+		case n == a.nodes[parent].left && a.black(rniecew):
+			a.nodes[sibling].red = true
+			a.nodes[lniecew].red = false
+			a.rotateRight(sibling)
+		case n == a.nodes[parent].right && a.black(lniecew):
+			a.nodes[sibling].red = true
+			a.nodes[rniecew].red = false
+			a.rotateLeft(sibling)
+		}
+		// Case 3
+		sibling = a.sibling(n, parent)
+		lniecew, rniecew = a.nodes[sibling].left, a.nodes[sibling].right
+		a.nodes[sibling].red = a.nodes[parent].red
+		a.nodes[parent].red = false
+		if n == a.nodes[parent].left {
+			if rniecew != 0 {
+				a.nodes[rniecew].red = false
+			}
+			a.rotateLeft(parent)
+		} else {
+			if lniecew != 0 {
+				a.nodes[lniecew].red = false
+			}
+			a.rotateRight(parent)
+		}
+		return
+	}
+}
+
+// ReplaceOrInsert adds the given interval → value mapping to this tree. If the
+// interval already exists in the tree, the previous value is returned with
+// present == true. Otherwise, (nil, false) is returned.
+func (a *ArenaTree) ReplaceOrInsert(iv Interval, value interface{}) (
+	previous interface{}, present bool,
+) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	var parent uint32
+	link := &a.root
+	for current := a.root; current != 0; current = *link {
+		switch c := iv.compare(a.nodes[current].interval); {
+		case c > 0:
+			link = &a.nodes[current].right
+		case c < 0:
+			link = &a.nodes[current].left
+		default:
+			previous = a.nodes[current].value
+			a.nodes[current].value = value
+			return previous, true
+		}
+		parent = current
+	}
+	// alloc may move the node slice, so the link is recomputed.
+	i := a.alloc(iv, value)
+	a.nodes[i].parent = parent
+	switch {
+	case parent == 0:
+		a.root = i
+	case iv.Less(a.nodes[parent].interval):
+		a.nodes[parent].left = i
+	default:
+		a.nodes[parent].right = i
+	}
+	a.updateAncestors(i)
+	a.rebalanceRed(i)
+	a.length++
+	return nil, false
+}
+
+// find returns the index of the node with the given interval, or 0 if no such
+// node exists.
+func (a *ArenaTree) find(iv Interval) uint32 {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	current := a.root
+	for current != 0 {
+		switch c := iv.compare(a.nodes[current].interval); {
+		case c < 0:
+			current = a.nodes[current].left
+		case c > 0:
+			current = a.nodes[current].right
+		default:
+			return current
+		}
+	}
+	return 0
+}
+
+// Get retrieves the value for the specified interval. If the given interval
+// is not part of this tree, (nil, false) is returned. Otherwise, the value and
+// present == true is returned.
+func (a *ArenaTree) Get(iv Interval) (value interface{}, present bool) {
+	i := a.find(iv)
+	if i == 0 {
+		return nil, false
+	}
+	return a.nodes[i].value, true
+}
+
+// Delete deletes the node for the specified interval from the tree.
+// If no such node exists, (nil, false) is returned. Otherwise, the value of
+// the deleted node is returned with deleted == true. The slot of the deleted
+// node is reused by later insertions.
+func (a *ArenaTree) Delete(iv Interval) (value interface{}, deleted bool) {
+	n := a.find(iv)
+	if n == 0 {
+		return nil, false
+	}
+	value = a.nodes[n].value
+	if a.nodes[n].left != 0 && a.nodes[n].right != 0 {
+		// n has two children, so we move the maximum lower node into the slot
+		// of n and delete that node instead. Since nodes are only addressed
+		// through the tree, there is no need to keep n's slot.
+		candidate := a.nodes[n].left
+		for a.nodes[candidate].right != 0 {
+			candidate = a.nodes[candidate].right
+		}
+		a.nodes[n].interval = a.nodes[candidate].interval
+		a.nodes[n].value = a.nodes[candidate].value
+		n = candidate
+	}
+
+	// At this point, n has at most one child, so we just have to unlink n from
+	// the tree and link the parent of n with the child.
+	parent := a.nodes[n].parent
+	child := a.nodes[n].left
+	if child == 0 {
+		child = a.nodes[n].right
+	}
+	a.replaceChild(parent, n, child)
+	a.updateAncestors(parent)
+	if !a.nodes[n].red {
+		if !a.black(child) {
+			a.nodes[child].red = false
+		} else {
+			a.rebalanceBlack(parent, child)
+		}
+	}
+	a.release(n)
+	a.length--
+	return value, true
+}
+
+// entry returns the entry for the node with the given index.
+func (a *ArenaTree) entry(i uint32) Entry {
+	return Entry{a.nodes[i].interval, a.nodes[i].value}
+}
+
+// Ascend calls visit for all intervals in this tree with their values in
+// sort-order (see Interval.Less). If visit returns false, the traversal stops.
+// visit must not modify this tree.
+func (a *ArenaTree) Ascend(visit func(Entry) bool) {
+	a.ascend(a.root, visit)
+}
+
+// ascend calls visit for all nodes in the subtree defined by the node with
+// the given index in sort-order, and reports whether the traversal should
+// continue.
+func (a *ArenaTree) ascend(i uint32, visit func(Entry) bool) bool {
+	if i == 0 {
+		return true
+	}
+	return a.ascend(a.nodes[i].left, visit) && visit(a.entry(i)) &&
+		a.ascend(a.nodes[i].right, visit)
+}
+
+// collectAscending calls the given descending traversal with a function
+// collecting all visited entries, and returns them in ascending order.
+func collectAscending(descend func(visit func(Entry) bool)) []Entry {
+	var list []Entry
+	descend(func(e Entry) bool {
+		list = append(list, e)
+		return true
+	})
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+// ContainingPoint returns all intervals containing the given point with their
+// values, ordered by their intervals, like T.NodesContainingPoint.
+func (a *ArenaTree) ContainingPoint(p Point) []Entry {
+	return collectAscending(func(visit func(Entry) bool) {
+		a.DescendContainingPoint(p, visit)
+	})
+}
+
+// DescendContainingPoint calls visit for all intervals containing the given
+// point with their values, in descending order of the intervals, until visit
+// returns false, like T.DescendContainingPoint. visit must not modify this
+// tree.
+func (a *ArenaTree) DescendContainingPoint(p Point, visit func(Entry) bool) {
+	a.descendContainingPoint(a.root, p, visit)
+}
+
+// descendContainingPoint calls visit for all nodes in the subtree defined by
+// the node with index i whose interval contains the given point, in
+// descending order. If visit returns false, the traversal stops and false is
+// returned.
+func (a *ArenaTree) descendContainingPoint(
+	i uint32, p Point, visit func(Entry) bool,
+) bool {
+	if i == 0 || lessOrEqual(a.nodes[i].maxRight, p) {
+		return true
+	}
+	n := &a.nodes[i]
+	if lessOrEqual(n.interval.Left, p) {
+		if !a.descendContainingPoint(n.right, p, visit) {
+			return false
+		}
+		if p.Less(n.interval.Right) && !visit(a.entry(i)) {
+			return false
+		}
+	}
+	return a.descendContainingPoint(n.left, p, visit)
+}
+
+// ContainingInterval returns all intervals containing the given interval with
+// their values, ordered by their intervals, like T.NodesContainingInterval.
+func (a *ArenaTree) ContainingInterval(iv Interval) []Entry {
+	return collectAscending(func(visit func(Entry) bool) {
+		a.DescendContainingInterval(iv, visit)
+	})
+}
+
+// DescendContainingInterval calls visit for all intervals containing the given
+// interval with their values, in descending order of the intervals, until
+// visit returns false, like T.DescendContainingInterval. visit must not modify
+// this tree.
+func (a *ArenaTree) DescendContainingInterval(
+	iv Interval, visit func(Entry) bool,
+) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	a.descendContainingInterval(a.root, iv, visit)
+}
+
+// descendContainingInterval calls visit for all nodes in the subtree defined
+// by the node with index i whose interval contains the given interval, in
+// descending order. If visit returns false, the traversal stops and false is
+// returned.
+func (a *ArenaTree) descendContainingInterval(
+	i uint32, iv Interval, visit func(Entry) bool,
+) bool {
+	if i == 0 || a.nodes[i].maxRight.Less(iv.Right) {
+		return true
+	}
+	n := &a.nodes[i]
+	if lessOrEqual(n.interval.Left, iv.Left) {
+		if !a.descendContainingInterval(n.right, iv, visit) {
+			return false
+		}
+		if lessOrEqual(iv.Right, n.interval.Right) && !visit(a.entry(i)) {
+			return false
+		}
+	}
+	return a.descendContainingInterval(n.left, iv, visit)
+}
+
+// ContainedInInterval returns all intervals contained in the given interval
+// with their values, ordered by their intervals, like
+// T.NodesContainedInInterval.
+func (a *ArenaTree) ContainedInInterval(iv Interval) []Entry {
+	return collectAscending(func(visit func(Entry) bool) {
+		a.DescendContainedInInterval(iv, visit)
+	})
+}
+
+// DescendContainedInInterval calls visit for all intervals contained in the
+// given interval with their values, in descending order of the intervals,
+// until visit returns false, like T.DescendContainedInInterval. visit must not
+// modify this tree.
+func (a *ArenaTree) DescendContainedInInterval(
+	iv Interval, visit func(Entry) bool,
+) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	a.descendContainedInInterval(a.root, iv, visit)
+}
+
+// descendContainedInInterval calls visit for all nodes in the subtree defined
+// by the node with index i whose interval is contained in the given interval,
+// in descending order. If visit returns false, the traversal stops and false
+// is returned.
+func (a *ArenaTree) descendContainedInInterval(
+	i uint32, iv Interval, visit func(Entry) bool,
+) bool {
+	if i == 0 || lessOrEqual(a.nodes[i].maxRight, iv.Left) {
+		return true
+	}
+	n := &a.nodes[i]
+	if n.interval.Left.Less(iv.Right) {
+		if !a.descendContainedInInterval(n.right, iv, visit) {
+			return false
+		}
+		if lessOrEqual(iv.Left, n.interval.Left) &&
+			lessOrEqual(n.interval.Right, iv.Right) && !visit(a.entry(i)) {
+			return false
+		}
+	}
+	if lessOrEqual(iv.Left, n.interval.Left) {
+		return a.descendContainedInInterval(n.left, iv, visit)
+	}
+	return true
+}
+
+// OverlappingInterval returns all intervals overlapping with the given
+// interval with their values, ordered by their intervals, like
+// T.NodesOverlappingInterval.
+func (a *ArenaTree) OverlappingInterval(iv Interval) []Entry {
+	return collectAscending(func(visit func(Entry) bool) {
+		a.DescendOverlappingInterval(iv, visit)
+	})
+}
+
+// DescendOverlappingInterval calls visit for all intervals overlapping with
+// the given interval with their values, in descending order of the intervals,
+// until visit returns false, like T.DescendOverlappingInterval. visit must not
+// modify this tree.
+func (a *ArenaTree) DescendOverlappingInterval(
+	iv Interval, visit func(Entry) bool,
+) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	a.descendOverlappingInterval(a.root, iv, visit)
+}
+
+// descendOverlappingInterval calls visit for all nodes in the subtree defined
+// by the node with index i whose interval overlaps with the given interval, in
+// descending order. If visit returns false, the traversal stops and false is
+// returned.
+func (a *ArenaTree) descendOverlappingInterval(
+	i uint32, iv Interval, visit func(Entry) bool,
+) bool {
+	if i == 0 || lessOrEqual(a.nodes[i].maxRight, iv.Left) {
+		return true
+	}
+	n := &a.nodes[i]
+	if n.interval.Left.Less(iv.Right) {
+		if !a.descendOverlappingInterval(n.right, iv, visit) {
+			return false
+		}
+		if iv.Left.Less(n.interval.Right) && !visit(a.entry(i)) {
+			return false
+		}
+	}
+	return a.descendOverlappingInterval(n.left, iv, visit)
+}
+
+// min returns the index of the lowest-sorting node in the subtree defined by
+// the node with index i, or 0 if i is 0.
+func (a *ArenaTree) min(i uint32) uint32 {
+	for i != 0 && a.nodes[i].left != 0 {
+		i = a.nodes[i].left
+	}
+	return i
+}
+
+// max returns the index of the highest-sorting node in the subtree defined by
+// the node with index i, or 0 if i is 0.
+func (a *ArenaTree) max(i uint32) uint32 {
+	for i != 0 && a.nodes[i].right != 0 {
+		i = a.nodes[i].right
+	}
+	return i
+}
+
+// next returns the index of the node following the node with index i in
+// sort-order, or 0 if there is no such node.
+func (a *ArenaTree) next(i uint32) uint32 {
+	if right := a.nodes[i].right; right != 0 {
+		return a.min(right)
+	}
+	for {
+		parent := a.nodes[i].parent
+		if parent == 0 || a.nodes[parent].left == i {
+			return parent
+		}
+		i = parent
+	}
+}
+
+// previous returns the index of the node preceding the node with index i in
+// sort-order, or 0 if there is no such node.
+func (a *ArenaTree) previous(i uint32) uint32 {
+	if left := a.nodes[i].left; left != 0 {
+		return a.max(left)
+	}
+	for {
+		parent := a.nodes[i].parent
+		if parent == 0 || a.nodes[parent].right == i {
+			return parent
+		}
+		i = parent
+	}
+}
+
+// lookupEntry returns the entry for the node with index i with ok == true, or
+// ok == false if i is 0.
+func (a *ArenaTree) lookupEntry(i uint32) (e Entry, ok bool) {
+	if i == 0 {
+		return Entry{}, false
+	}
+	return a.entry(i), true
+}
+
+// GetMin returns the lowest-sorting interval (see Interval.Less) in this tree
+// with its value and ok == true. If the tree is empty, ok is false.
+func (a *ArenaTree) GetMin() (e Entry, ok bool) {
+	return a.lookupEntry(a.min(a.root))
+}
+
+// GetMax returns the highest-sorting interval (see Interval.Less) in this tree
+// with its value and ok == true. If the tree is empty, ok is false.
+func (a *ArenaTree) GetMax() (e Entry, ok bool) {
+	return a.lookupEntry(a.max(a.root))
+}
+
+// Next returns the lowest-sorting interval in this tree greater than the given
+// interval, which need not be part of the tree, with its value and
+// ok == true, like T.GetGreater. If no such interval exists, ok is false.
+// Since there are no node references, each step of an iteration with Next
+// runs in O(log(n)) time, where n is the size of this tree; Ascend and
+// Descend iterate in O(1) amortized time per step.
+func (a *ArenaTree) Next(iv Interval) (e Entry, ok bool) {
+	var candidate uint32
+	for current := a.root; current != 0; {
+		if iv.Less(a.nodes[current].interval) {
+			candidate = current
+			current = a.nodes[current].left
+		} else {
+			current = a.nodes[current].right
+		}
+	}
+	return a.lookupEntry(candidate)
+}
+
+// Previous returns the highest-sorting interval in this tree less than the
+// given interval, which need not be part of the tree, with its value and
+// ok == true, like T.GetLess. If no such interval exists, ok is false.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (a *ArenaTree) Previous(iv Interval) (e Entry, ok bool) {
+	return a.lookupEntry(a.lessEqual(iv, false))
+}
+
+// lessEqual returns the index of the highest-sorting node whose interval is
+// less than the given interval, or equal to it if orEqual is true, or 0 if
+// there is no such node.
+func (a *ArenaTree) lessEqual(iv Interval, orEqual bool) uint32 {
+	var candidate uint32
+	for current := a.root; current != 0; {
+		c := a.nodes[current].interval.compare(iv)
+		if c < 0 || (orEqual && c == 0) {
+			candidate = current
+			current = a.nodes[current].right
+		} else {
+			current = a.nodes[current].left
+		}
+	}
+	return candidate
+}
+
+// Descend calls visit for all intervals less than or equal to the given
+// interval with their values, in descending order, until visit returns false,
+// like T.Descend. visit must not modify this tree.
+func (a *ArenaTree) Descend(from Interval, visit func(Entry) bool) {
+	for i := a.lessEqual(from, true); i != 0 && visit(a.entry(i)); {
+		i = a.previous(i)
+	}
+}
+
+// NextStart returns the lowest left endpoint s in this tree with p <= s,
+// along with all intervals starting at s and their values, ordered by their
+// intervals, like T.NextStart. If no such endpoint exists, (nil, nil) is
+// returned.
+// This operation runs in O(m+log(n)) time, where m is the number of returned
+// entries and n is the size of this tree.
+func (a *ArenaTree) NextStart(p Point) (Point, []Entry) {
+	var candidate uint32
+	for current := a.root; current != 0; {
+		if a.nodes[current].interval.Left.Less(p) {
+			current = a.nodes[current].right
+		} else {
+			candidate = current
+			current = a.nodes[current].left
+		}
+	}
+	if candidate == 0 {
+		return nil, nil
+	}
+	start := a.nodes[candidate].interval.Left
+	var result []Entry
+	for i := candidate; i != 0 && equal(a.nodes[i].interval.Left, start); {
+		result = append(result, a.entry(i))
+		i = a.next(i)
+	}
+	return start, result
+}
+
+// NextEnd returns the lowest right endpoint e in this tree with p <= e, along
+// with all intervals ending at e and their values, ordered by their
+// intervals, like T.NextEnd without the end index.
+// If no such endpoint exists, (nil, nil) is returned.
+// This operation runs in O(k+log(n)) time, where k is the number of intervals
+// starting before e and ending at or after p, and n is the size of this tree.
+func (a *ArenaTree) NextEnd(p Point) (Point, []Entry) {
+	end, list, _ := a.nextEnd(a.root, p, nil, nil)
+	var result []Entry
+	for _, i := range list {
+		result = append(result, a.entry(i))
+	}
+	return end, result
+}
+
+// nextEnd searches the subtree defined by the node with index i for the
+// lowest right endpoint e with p <= e, like Node.nextEnd. The given best is
+// the lowest such endpoint found so far (nil if none), and list contains the
+// indices of the nodes ending at best.
+func (a *ArenaTree) nextEnd(i uint32, p, best Point, list []uint32) (
+	newBest Point, newList []uint32, done bool,
+) {
+	if i == 0 || a.nodes[i].maxRight.Less(p) {
+		return best, list, false
+	}
+	n := &a.nodes[i]
+	best, list, done = a.nextEnd(n.left, p, best, list)
+	if done {
+		return best, list, true
+	}
+	if best != nil && lessOrEqual(best, n.interval.Left) {
+		return best, list, true
+	}
+	if lessOrEqual(p, n.interval.Right) {
+		switch {
+		case best == nil || n.interval.Right.Less(best):
+			best = n.interval.Right
+			list = append(list[:0], i)
+		case equal(n.interval.Right, best):
+			list = append(list, i)
+		}
+	}
+	return a.nextEnd(n.right, p, best, list)
+}
+
+// Compact renumbers the nodes of this tree in sort-order and drops all free
+// slots, so that sequential scans access memory sequentially.
+// This operation runs in O(n) time, where n is the size of this tree.
+func (a *ArenaTree) Compact() {
+	if len(a.nodes) == 0 {
+		return
+	}
+	index := make([]uint32, len(a.nodes))
+	next := uint32(1)
+	var number func(i uint32)
+	number = func(i uint32) {
+		if i == 0 {
+			return
+		}
+		number(a.nodes[i].left)
+		index[i] = next
+		next++
+		number(a.nodes[i].right)
+	}
+	number(a.root)
+	nodes := make([]arenaNode, next)
+	for i, j := range index {
+		if j == 0 {
+			continue
+		}
+		n := a.nodes[i]
+		n.parent, n.left, n.right = index[n.parent], index[n.left], index[n.right]
+		nodes[j] = n
+	}
+	a.nodes, a.root, a.free = nodes, index[a.root], 0
+}
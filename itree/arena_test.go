@@ -0,0 +1,274 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testArenaInvariants tests the tree invariants of the given arena tree.
+func testArenaInvariants(t *testing.T, a *ArenaTree) {
+	var check func(i, parent uint32) (blackDepth, size int)
+	check = func(i, parent uint32) (int, int) {
+		if i == 0 {
+			return 1, 0
+		}
+		n := a.nodes[i]
+		if n.parent != parent {
+			t.Errorf("node %d has parent %d, expected %d", i, n.parent, parent)
+		}
+		if n.red && !(a.black(n.left) && a.black(n.right)) {
+			t.Errorf("red node %d with red child", i)
+		}
+		expectedMaxRight := n.interval.Right
+		for _, child := range [...]uint32{n.left, n.right} {
+			if child != 0 && expectedMaxRight.Less(a.nodes[child].maxRight) {
+				expectedMaxRight = a.nodes[child].maxRight
+			}
+		}
+		if !equal(expectedMaxRight, n.maxRight) {
+			t.Errorf("node %d expected maxRight %v, have %v", i,
+				expectedMaxRight, n.maxRight)
+		}
+		leftDepth, leftSize := check(n.left, i)
+		rightDepth, rightSize := check(n.right, i)
+		if leftDepth != rightDepth {
+			t.Errorf("node %d has black depths %d and %d", i, leftDepth,
+				rightDepth)
+		}
+		if !n.red {
+			leftDepth++
+		}
+		return leftDepth, leftSize + rightSize + 1
+	}
+	if !a.black(a.root) {
+		t.Error("red root")
+	}
+	if _, size := check(a.root, 0); size != a.Len() {
+		t.Errorf("expected size %d, got %d", a.Len(), size)
+	}
+	var last *Interval
+	a.Ascend(func(e Entry) bool {
+		if last != nil && !last.Less(e.Interval) {
+			t.Errorf("intervals %v and %v out of order", *last, e.Interval)
+		}
+		last = &e.Interval
+		return true
+	})
+}
+
+// checkArenaEntries checks that the given entries match the given nodes.
+// Errors are logged to t, prefixed with what.
+func checkArenaEntries(t *testing.T, what string, entries []Entry,
+	nodes []*Node) {
+	if len(entries) != len(nodes) {
+		t.Errorf("%s: expected %d entries, got %d", what, len(nodes),
+			len(entries))
+		return
+	}
+	for i, e := range entries {
+		if !e.Interval.Equal(nodes[i].interval) || e.Value != nodes[i].Value {
+			t.Errorf("%s: expected %v → %v at %d, got %v → %v", what,
+				nodes[i].interval, nodes[i].Value, i, e.Interval, e.Value)
+		}
+	}
+}
+
+// checkArenaDescend checks that the given descending traversal visits entries
+// matching the given nodes in reverse order, and that it stops when visit
+// returns false. Errors are logged to t, prefixed with what.
+func checkArenaDescend(t *testing.T, what string,
+	descend func(visit func(Entry) bool), nodes []*Node) {
+	var entries []Entry
+	descend(func(e Entry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	checkArenaEntries(t, what, entries, nodes)
+	visited := 0
+	descend(func(Entry) bool {
+		visited++
+		return false
+	})
+	if visited > 1 || (len(nodes) > 0) != (visited > 0) {
+		t.Errorf("%s: visited %d entries after stop", what, visited)
+	}
+}
+
+// checkArenaOptional checks that the given arena lookup matches the given
+// tree lookup for iv. Errors are logged to t, prefixed with what.
+func checkArenaOptional(t *testing.T, what string,
+	lookup func(Interval) (Entry, bool), expected func(Interval) *Node,
+	iv Interval) {
+	e, ok := lookup(iv)
+	n := expected(iv)
+	if ok != (n != nil) || ok && (!e.Interval.Equal(n.interval) ||
+		e.Value != n.Value) {
+		t.Errorf("%s(%v): expected %v, got %v, %t", what, iv, n, e, ok)
+	}
+}
+
+// equalPoints reports whether the given points are equal or both nil.
+func equalPoints(x, y Point) bool {
+	if x == nil || y == nil {
+		return x == nil && y == nil
+	}
+	return equal(x, y)
+}
+
+// TestArenaTreeRandom compares random arena trees with T.
+func TestArenaTreeRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var a ArenaTree
+	var tree T
+	for i := 0; i != 2000; i++ {
+		iv := randomInterval()
+		if rand.Intn(3) == 0 {
+			if n := tree.GetMin(); n != nil {
+				iv = n.interval
+			}
+			value, deleted := a.Delete(iv)
+			expected, expectedDeleted := tree.Delete(iv)
+			if deleted != expectedDeleted || value != expected {
+				t.Errorf("delete %v: expected %v, got %v", iv, expected, value)
+			}
+			continue
+		}
+		previous, present := a.ReplaceOrInsert(iv, i)
+		expected, expectedPresent := tree.ReplaceOrInsert(iv, i)
+		if present != expectedPresent || previous != expected {
+			t.Errorf("insert %v: expected %v, got %v", iv, expected, previous)
+		}
+	}
+	testArenaInvariants(t, &a)
+	if a.Len() != tree.Len() {
+		t.Fatalf("expected %d nodes, got %d", tree.Len(), a.Len())
+	}
+	check := func() {
+		for i := 0; i != 100; i++ {
+			iv := randomInterval()
+			checkArenaEntries(t, "ContainingPoint", a.ContainingPoint(iv.Left),
+				tree.NodesContainingPoint(iv.Left))
+			checkArenaEntries(t, "ContainingInterval", a.ContainingInterval(iv),
+				tree.NodesContainingInterval(iv))
+			checkArenaEntries(t, "ContainedInInterval", a.ContainedInInterval(iv),
+				tree.NodesContainedInInterval(iv))
+			checkArenaEntries(t, "OverlappingInterval", a.OverlappingInterval(iv),
+				tree.NodesOverlappingInterval(iv))
+			checkArenaDescend(t, "DescendContainingPoint",
+				func(visit func(Entry) bool) {
+					a.DescendContainingPoint(iv.Left, visit)
+				}, tree.NodesContainingPoint(iv.Left))
+			checkArenaDescend(t, "DescendContainingInterval",
+				func(visit func(Entry) bool) {
+					a.DescendContainingInterval(iv, visit)
+				}, tree.NodesContainingInterval(iv))
+			checkArenaDescend(t, "DescendContainedInInterval",
+				func(visit func(Entry) bool) {
+					a.DescendContainedInInterval(iv, visit)
+				}, tree.NodesContainedInInterval(iv))
+			checkArenaDescend(t, "DescendOverlappingInterval",
+				func(visit func(Entry) bool) {
+					a.DescendOverlappingInterval(iv, visit)
+				}, tree.NodesOverlappingInterval(iv))
+			var less []*Node
+			tree.Descend(iv, func(n *Node) bool {
+				less = append(less, n)
+				return true
+			})
+			for i, j := 0, len(less)-1; i < j; i, j = i+1, j-1 {
+				less[i], less[j] = less[j], less[i]
+			}
+			checkArenaDescend(t, "Descend", func(visit func(Entry) bool) {
+				a.Descend(iv, visit)
+			}, less)
+			checkArenaOptional(t, "Next", a.Next, tree.GetGreater, iv)
+			checkArenaOptional(t, "Previous", a.Previous, tree.GetLess, iv)
+			start, starting := a.NextStart(iv.Left)
+			expectedStart, expectedStarting := tree.NextStart(iv.Left)
+			if !equalPoints(start, expectedStart) {
+				t.Errorf("NextStart: expected %v, got %v", expectedStart, start)
+			}
+			checkArenaEntries(t, "NextStart", starting, expectedStarting)
+			end, ending := a.NextEnd(iv.Left)
+			expectedEnd, expectedEnding := tree.NextEnd(iv.Left)
+			if !equalPoints(end, expectedEnd) {
+				t.Errorf("NextEnd: expected %v, got %v", expectedEnd, end)
+			}
+			checkArenaEntries(t, "NextEnd", ending, expectedEnding)
+		}
+		min, ok := a.GetMin()
+		if ok != (tree.Len() != 0) ||
+			ok && !min.Interval.Equal(tree.GetMin().interval) {
+			t.Errorf("GetMin: unexpected %v", min)
+		}
+		max, ok := a.GetMax()
+		if ok != (tree.Len() != 0) ||
+			ok && !max.Interval.Equal(tree.GetMax().interval) {
+			t.Errorf("GetMax: unexpected %v", max)
+		}
+	}
+	check()
+	// Modify the tree after the first queries.
+	for i := 0; i != 200; i++ {
+		iv := randomInterval()
+		if i%2 == 0 {
+			a.ReplaceOrInsert(iv, i)
+			tree.ReplaceOrInsert(iv, i)
+		} else if n := tree.GetMin(); n != nil {
+			a.Delete(n.interval)
+			tree.DeleteNode(n)
+		}
+	}
+	check()
+	a.Compact()
+	testArenaInvariants(t, &a)
+	if len(a.nodes) != a.Len()+1 || a.free != 0 {
+		t.Errorf("expected %d slots after compaction, got %d", a.Len()+1,
+			len(a.nodes))
+	}
+	next := uint32(1)
+	a.Ascend(func(e Entry) bool {
+		if n := a.nodes[next]; !n.interval.Equal(e.Interval) {
+			t.Errorf("node %d not in sort-order after compaction", next)
+		}
+		next++
+		return true
+	})
+	check()
+}
+
+// TestArenaTreeFreeList tests that deleted slots are reused.
+func TestArenaTreeFreeList(t *testing.T) {
+	a := NewArenaTree(10)
+	for i := 0; i != 10; i++ {
+		a.ReplaceOrInsert(Interval{Int(i), Int(i + 1)}, i)
+	}
+	slots := len(a.nodes)
+	for i := 0; i != 5; i++ {
+		a.Delete(Interval{Int(2 * i), Int(2*i + 1)})
+	}
+	for i := 0; i != 5; i++ {
+		a.ReplaceOrInsert(Interval{Int(i), Int(i + 2)}, i)
+	}
+	testArenaInvariants(t, a)
+	if len(a.nodes) != slots {
+		t.Errorf("expected %d slots, got %d", slots, len(a.nodes))
+	}
+	var visited int
+	a.Ascend(func(Entry) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Errorf("expected Ascend to stop after 3 entries, got %d", visited)
+	}
+	if _, present := a.Get(Interval{Int(1), Int(2)}); !present {
+		t.Error("missing interval [1,2)")
+	}
+	if _, present := a.Get(Interval{Int(0), Int(1)}); present {
+		t.Error("deleted interval [0,1) present")
+	}
+}
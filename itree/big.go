@@ -0,0 +1,160 @@
+package itree
+
+import (
+	"math/big"
+)
+
+// BigInt implements Point for arbitrary-precision integers, such as 256-bit
+// keys, using big.Int.Cmp. BigInt points are created with BigIntOf.
+//
+// The tree only stores shallow copies of points (see Node.Interval), so a
+// BigInt must not share its big.Int with code that might modify it. Therefore,
+// BigIntOf stores a copy of its argument, and Int returns a copy, so that a
+// BigInt is immutable.
+type BigInt struct {
+	// x is the integer. It is never modified.
+	x *big.Int
+}
+
+// BigIntOf returns a BigInt with the value of the given integer.
+func BigIntOf(x *big.Int) BigInt {
+	return BigInt{new(big.Int).Set(x)}
+}
+
+// Int returns the value of this point as a newly allocated big.Int.
+func (x BigInt) Int() *big.Int {
+	return new(big.Int).Set(x.x)
+}
+
+// String returns the value of this point in base 10.
+func (x BigInt) String() string {
+	return x.x.String()
+}
+
+// Less checks whether x < y. It panics if y is not BigInt.
+func (x BigInt) Less(y Point) bool {
+	return x.x.Cmp(y.(BigInt).x) < 0
+}
+
+// Compare implements Comparer. It panics if y is not BigInt.
+func (x BigInt) Compare(y Point) int {
+	return x.x.Cmp(y.(BigInt).x)
+}
+
+// Distance implements Metric. The distance is rounded to the nearest float64.
+// It panics if y is not BigInt.
+func (x BigInt) Distance(y Point) float64 {
+	d := new(big.Int).Sub(y.(BigInt).x, x.x)
+	f, _ := new(big.Float).SetInt(d).Float64()
+	return f
+}
+
+// Succ implements Discrete.
+func (x BigInt) Succ() (Point, bool) {
+	return BigInt{new(big.Int).Add(x.x, big.NewInt(1))}, true
+}
+
+// Pred implements Discrete.
+func (x BigInt) Pred() (Point, bool) {
+	return BigInt{new(big.Int).Sub(x.x, big.NewInt(1))}, true
+}
+
+// BigRat implements Point for arbitrary-precision rational numbers, e. g., for
+// exact rational times, using big.Rat.Cmp. BigRat points are created with
+// BigRatOf, and like BigInt, they are immutable.
+type BigRat struct {
+	// x is the rational number. It is never modified.
+	x *big.Rat
+}
+
+// BigRatOf returns a BigRat with the value of the given rational number.
+func BigRatOf(x *big.Rat) BigRat {
+	return BigRat{new(big.Rat).Set(x)}
+}
+
+// Rat returns the value of this point as a newly allocated big.Rat.
+func (x BigRat) Rat() *big.Rat {
+	return new(big.Rat).Set(x.x)
+}
+
+// String returns the value of this point as a fraction "a/b".
+func (x BigRat) String() string {
+	return x.x.String()
+}
+
+// Less checks whether x < y. It panics if y is not BigRat.
+func (x BigRat) Less(y Point) bool {
+	return x.x.Cmp(y.(BigRat).x) < 0
+}
+
+// Compare implements Comparer. It panics if y is not BigRat.
+func (x BigRat) Compare(y Point) int {
+	return x.x.Cmp(y.(BigRat).x)
+}
+
+// Distance implements Metric. The distance is rounded to the nearest float64.
+// It panics if y is not BigRat.
+func (x BigRat) Distance(y Point) float64 {
+	f, _ := new(big.Rat).Sub(y.(BigRat).x, x.x).Float64()
+	return f
+}
+
+// BigFloat implements Point for arbitrary-precision floating-point numbers,
+// using big.Float.Cmp. Infinities are permitted; +0 and -0 are considered to
+// be equal. BigFloat points are created with BigFloatOf, and like BigInt, they
+// are immutable.
+type BigFloat struct {
+	// x is the floating-point number. It is never modified.
+	x *big.Float
+}
+
+// BigFloatOf returns a BigFloat with the value and precision of the given
+// floating-point number.
+func BigFloatOf(x *big.Float) BigFloat {
+	return BigFloat{new(big.Float).Copy(x)}
+}
+
+// Float returns the value of this point as a newly allocated big.Float with
+// the precision of the point.
+func (x BigFloat) Float() *big.Float {
+	return new(big.Float).Copy(x.x)
+}
+
+// String returns the value of this point like big.Float.String.
+func (x BigFloat) String() string {
+	return x.x.String()
+}
+
+// Less checks whether x < y. It panics if y is not BigFloat.
+func (x BigFloat) Less(y Point) bool {
+	return x.x.Cmp(y.(BigFloat).x) < 0
+}
+
+// Compare implements Comparer. It panics if y is not BigFloat.
+func (x BigFloat) Compare(y Point) int {
+	return x.x.Cmp(y.(BigFloat).x)
+}
+
+// Distance implements Metric. The distance is computed with the larger
+// precision of both points and rounded to the nearest float64. It panics if y
+// is not BigFloat, or if both points are infinities of the same sign.
+func (x BigFloat) Distance(y Point) float64 {
+	yf := y.(BigFloat).x
+	prec := x.x.Prec()
+	if yf.Prec() > prec {
+		prec = yf.Prec()
+	}
+	f, _ := new(big.Float).SetPrec(prec).Sub(yf, x.x).Float64()
+	return f
+}
+
+// Point interface checks for arbitrary-precision points.
+var (
+	_ = []interface {
+		Comparer
+		Metric
+	}{
+		BigInt{}, BigRat{}, BigFloat{},
+	}
+	_ Discrete = BigInt{}
+)
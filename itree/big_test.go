@@ -0,0 +1,109 @@
+package itree
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// bigInt returns the integer 2^exp + delta as BigInt.
+func bigInt(exp uint, delta int64) BigInt {
+	x := new(big.Int).Lsh(big.NewInt(1), exp)
+	return BigIntOf(x.Add(x, big.NewInt(delta)))
+}
+
+// TestBigInt tests BigInt points beyond the range of machine integers.
+func TestBigInt(t *testing.T) {
+	var tree T
+	tree.ReplaceOrInsert(Interval{bigInt(128, 0), bigInt(128, 10)}, "a")
+	tree.ReplaceOrInsert(Interval{bigInt(128, 5), bigInt(129, 0)}, "b")
+	tree.ReplaceOrInsert(Interval{bigInt(64, 0), bigInt(128, 0)}, "c")
+	testInvariants(t, &tree)
+	if nodes := tree.NodesContainingPoint(bigInt(128, 7)); len(nodes) != 2 {
+		t.Errorf("expected 2 nodes, got %d", len(nodes))
+	}
+	// Equal values in distinct big.Ints are equal points.
+	if _, present := tree.Get(Interval{bigInt(64, 0), bigInt(128, 0)}); !present {
+		t.Error("interval not found")
+	}
+	iv := Interval{bigInt(128, 0), bigInt(128, 10)}
+	if length := iv.Length(); length != 10 {
+		t.Errorf("expected length 10, got %g", length)
+	}
+	if d := bigInt(0, 0).Distance(bigInt(200, 0)); d != math.Ldexp(1, 200) {
+		t.Errorf("unexpected distance %g", d)
+	}
+	last := Closed(bigInt(128, 0), bigInt(128, 2)).Last()
+	if last.(BigInt).Int().Cmp(bigInt(128, 2).Int()) != 0 {
+		t.Errorf("expected last point 2^128+2, got %v", last)
+	}
+	var n int
+	iv.Points(func(Point) bool {
+		n++
+		return true
+	})
+	if n != 10 {
+		t.Errorf("expected 10 points, got %d", n)
+	}
+	// Computing the successor must not modify the point, and neither must
+	// modifying the argument of BigIntOf or the result of Int.
+	y := big.NewInt(256)
+	x := BigIntOf(y)
+	x.Succ()
+	x.Pred()
+	y.SetInt64(0)
+	x.Int().SetInt64(0)
+	if x.String() != "256" {
+		t.Errorf("point modified to %v", x)
+	}
+}
+
+// TestBigRat tests BigRat points.
+func TestBigRat(t *testing.T) {
+	third, half := BigRatOf(big.NewRat(1, 3)), BigRatOf(big.NewRat(1, 2))
+	if !third.Less(half) || half.Less(third) ||
+		third.Compare(BigRatOf(big.NewRat(2, 6))) != 0 {
+		t.Error("unexpected order")
+	}
+	iv := Interval{third, half}
+	if length := iv.Length(); math.Abs(length-1.0/6) > 1e-15 {
+		t.Errorf("expected length 1/6, got %g", length)
+	}
+	if !iv.ContainsPoint(BigRatOf(big.NewRat(2, 5))) {
+		t.Error("point not contained")
+	}
+	if s := third.String(); s != "1/3" || third.Rat().Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("unexpected value %s", s)
+	}
+}
+
+// TestBigFloat tests BigFloat points, including infinities.
+func TestBigFloat(t *testing.T) {
+	inf := BigFloatOf(new(big.Float).SetInf(false))
+	negInf := BigFloatOf(new(big.Float).SetInf(true))
+	zero := BigFloatOf(new(big.Float))
+	negZero := BigFloatOf(new(big.Float).Neg(zero.Float()))
+	if !negInf.Less(zero) || !zero.Less(inf) || zero.Compare(negZero) != 0 {
+		t.Error("unexpected order")
+	}
+	var tree T
+	tree.ReplaceOrInsert(Interval{negInf, zero}, "negative")
+	tree.ReplaceOrInsert(Interval{zero, inf}, "positive")
+	testInvariants(t, &tree)
+	if v, _ := tree.Get(Interval{negInf, negZero}); v != "negative" {
+		t.Errorf("expected negative, got %v", v)
+	}
+	if length := (Interval{zero, inf}).Length(); !math.IsInf(length, 1) {
+		t.Errorf("expected infinite length, got %g", length)
+	}
+	// The distance keeps the precision of the more precise point.
+	x := BigFloatOf(new(big.Float).SetPrec(200).SetInt64(1))
+	y := BigFloatOf(new(big.Float).SetPrec(200).Add(x.Float(),
+		new(big.Float).SetMantExp(big.NewFloat(1), -100)))
+	if d := x.Distance(y); d != math.Ldexp(1, -100) {
+		t.Errorf("expected distance 2^-100, got %g", d)
+	}
+	expectPanic(t, "distance of equal infinities", func() {
+		inf.Distance(inf)
+	})
+}
@@ -0,0 +1,192 @@
+package itree
+
+// BoxTree maps boxes, i. e., products of one interval per dimension, to
+// arbitrary values, and supports point-stabbing and box-overlap queries, e. g.,
+// for map tiles or time×frequency cells.
+//
+// A BoxTree is a nested interval tree: the first level is a tree over the
+// intervals of the first dimension, and each of its nodes holds a tree over
+// the intervals of the second dimension of all boxes sharing that first
+// interval, and so on. A query filters each level with the respective interval
+// tree query, so it is efficient as long as the boxes found in the leading
+// dimensions mostly match in the remaining dimensions as well.
+// The zero value of BoxTree is not usable; use NewBoxTree.
+type BoxTree struct {
+	// dims is the number of dimensions.
+	dims int
+
+	// length is the number of boxes.
+	length int
+
+	// root is the tree of the first dimension. The node values of the tree of
+	// the last dimension are the box values; those of the trees of the other
+	// dimensions are the trees of the respective next dimension.
+	root T
+}
+
+// BoxEntry is a box of a BoxTree along with its value.
+type BoxEntry struct {
+	Box   []Interval
+	Value interface{}
+}
+
+// NewBoxTree creates a new, empty box tree with the given positive number of
+// dimensions.
+func NewBoxTree(dims int) *BoxTree {
+	if dims < 1 {
+		panic("no dimensions")
+	}
+	return &BoxTree{
+		dims: dims,
+	}
+}
+
+// Dims returns the number of dimensions of this box tree.
+func (b *BoxTree) Dims() int {
+	return b.dims
+}
+
+// Len returns the number of boxes in this box tree.
+func (b *BoxTree) Len() int {
+	return b.length
+}
+
+// checkDims panics if the given number of dimensions does not match this box
+// tree.
+func (b *BoxTree) checkDims(n int) {
+	if n != b.dims {
+		panic("dimension mismatch")
+	}
+}
+
+// ReplaceOrInsert adds the given box → value mapping to this box tree. The box
+// is given by one non-empty interval per dimension. If the box already exists
+// in the tree, the previous value is returned with present == true.
+// Otherwise, (nil, false) is returned.
+func (b *BoxTree) ReplaceOrInsert(box []Interval, value interface{}) (
+	previous interface{}, present bool,
+) {
+	b.checkDims(len(box))
+	for _, iv := range box {
+		if iv.empty() {
+			panic("empty interval")
+		}
+	}
+	t := &b.root
+	for _, iv := range box[:b.dims-1] {
+		found, parent, link := t.locate(iv)
+		if found == nil {
+			found = &Node{
+				Value:    &T{},
+				interval: iv,
+			}
+			t.linkNode(found, parent, link)
+		}
+		t = found.Value.(*T)
+	}
+	found, parent, link := t.locate(box[b.dims-1])
+	if found != nil {
+		previous = found.Value
+		found.Value = value
+		return previous, true
+	}
+	t.linkNode(&Node{
+		Value:    value,
+		interval: box[b.dims-1],
+	}, parent, link)
+	b.length++
+	return nil, false
+}
+
+// path returns the nodes of the given box in the trees of all dimensions, or
+// nil if the box is not part of this box tree.
+func (b *BoxTree) path(box []Interval) []*Node {
+	b.checkDims(len(box))
+	path := make([]*Node, 0, len(box))
+	t := &b.root
+	for _, iv := range box {
+		n := t.GetNode(iv)
+		if n == nil {
+			return nil
+		}
+		path = append(path, n)
+		if len(path) < len(box) {
+			t = n.Value.(*T)
+		}
+	}
+	return path
+}
+
+// Get retrieves the value for the given box. If the box is not part of this
+// box tree, (nil, false) is returned. Otherwise, the value and present == true
+// is returned.
+func (b *BoxTree) Get(box []Interval) (value interface{}, present bool) {
+	path := b.path(box)
+	if path == nil {
+		return nil, false
+	}
+	return path[len(path)-1].Value, true
+}
+
+// Delete deletes the given box from this box tree. If the box is not part of
+// this box tree, (nil, false) is returned. Otherwise, the value of the deleted
+// box is returned with deleted == true. Trees of the inner dimensions which
+// become empty are removed as well.
+func (b *BoxTree) Delete(box []Interval) (value interface{}, deleted bool) {
+	path := b.path(box)
+	if path == nil {
+		return nil, false
+	}
+	value = path[len(path)-1].Value
+	for d := len(path) - 1; d >= 0; d-- {
+		t := &b.root
+		if d > 0 {
+			t = path[d-1].Value.(*T)
+		}
+		t.DeleteNode(path[d])
+		if t.Len() != 0 {
+			break
+		}
+	}
+	b.length--
+	return value, true
+}
+
+// query appends the entries of all boxes in the tree t of dimension d whose
+// nodes in t and in the trees of the following dimensions are selected by the
+// given function to result, and returns it. The given prefix contains the
+// intervals of the previous dimensions.
+func (b *BoxTree) query(
+	result []BoxEntry, t *T, d int, prefix []Interval,
+	selectNodes func(t *T, d int) []*Node,
+) []BoxEntry {
+	for _, n := range selectNodes(t, d) {
+		box := append(prefix[:d:d], n.interval)
+		if d == b.dims-1 {
+			result = append(result, BoxEntry{box, n.Value})
+		} else {
+			result = b.query(result, n.Value.(*T), d+1, box, selectNodes)
+		}
+	}
+	return result
+}
+
+// ContainingPoint returns all boxes containing the given point, which is given
+// by one coordinate per dimension, with their values. The boxes are ordered
+// lexicographically by their intervals (see Interval.Less).
+func (b *BoxTree) ContainingPoint(p ...Point) []BoxEntry {
+	b.checkDims(len(p))
+	return b.query(nil, &b.root, 0, nil, func(t *T, d int) []*Node {
+		return t.NodesContainingPoint(p[d])
+	})
+}
+
+// Overlapping returns all boxes overlapping with the given box, which is given
+// by one non-empty interval per dimension, with their values, ordered like in
+// ContainingPoint.
+func (b *BoxTree) Overlapping(box []Interval) []BoxEntry {
+	b.checkDims(len(box))
+	return b.query(nil, &b.root, 0, nil, func(t *T, d int) []*Node {
+		return t.NodesOverlappingInterval(box[d])
+	})
+}
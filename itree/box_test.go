@@ -0,0 +1,133 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomBox returns a random box with the given number of dimensions.
+func randomBox(dims int) []Interval {
+	box := make([]Interval, dims)
+	for d := range box {
+		left := Int(rand.Intn(20))
+		box[d] = Interval{left, left + 1 + Int(rand.Intn(5))}
+	}
+	return box
+}
+
+// boxKey returns a comparable key for the given box.
+func boxKey(box []Interval) [3]Interval {
+	var key [3]Interval
+	copy(key[:], box)
+	return key
+}
+
+// checkBoxEntries checks that the given entries are exactly the given boxes
+// with their values. Errors are logged to t, prefixed with what.
+func checkBoxEntries(t *testing.T, what string, entries []BoxEntry,
+	expected map[[3]Interval]interface{}) {
+	if len(entries) != len(expected) {
+		t.Errorf("%s: expected %d boxes, got %d", what, len(expected),
+			len(entries))
+		return
+	}
+	for i, e := range entries {
+		if value, ok := expected[boxKey(e.Box)]; !ok || value != e.Value {
+			t.Errorf("%s: unexpected box %v → %v", what, e.Box, e.Value)
+		}
+		if i > 0 && !boxLess(entries[i-1].Box, e.Box) {
+			t.Errorf("%s: boxes %v and %v out of order", what,
+				entries[i-1].Box, e.Box)
+		}
+	}
+}
+
+// boxLess compares the given boxes lexicographically.
+func boxLess(a, b []Interval) bool {
+	for d := range a {
+		switch {
+		case a[d].Less(b[d]):
+			return true
+		case b[d].Less(a[d]):
+			return false
+		}
+	}
+	return false
+}
+
+// TestBoxTreeRandom compares random box trees with brute force.
+func TestBoxTreeRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	for _, dims := range []int{1, 2, 3} {
+		b := NewBoxTree(dims)
+		checkMap := make(map[[3]Interval]interface{})
+		for i := 0; i != 500; i++ {
+			box := randomBox(dims)
+			previous, present := b.ReplaceOrInsert(box, i)
+			if value, ok := checkMap[boxKey(box)]; ok != present ||
+				previous != value {
+				t.Errorf("box %v: expected previous %v, got %v", box, value,
+					previous)
+			}
+			checkMap[boxKey(box)] = i
+		}
+		for i := 0; i != 200; i++ {
+			box := randomBox(dims)
+			value, deleted := b.Delete(box)
+			if expected, ok := checkMap[boxKey(box)]; ok != deleted ||
+				value != expected {
+				t.Errorf("delete box %v: expected %v, got %v", box, expected,
+					value)
+			}
+			delete(checkMap, boxKey(box))
+		}
+		if b.Len() != len(checkMap) {
+			t.Errorf("expected %d boxes, got %d", len(checkMap), b.Len())
+		}
+		for i := 0; i != 50; i++ {
+			query := randomBox(dims)
+			p := make([]Point, dims)
+			for d := range p {
+				p[d] = query[d].Left
+			}
+			overlapping := make(map[[3]Interval]interface{})
+			containing := make(map[[3]Interval]interface{})
+			for key, value := range checkMap {
+				overlaps, contains := true, true
+				for d := 0; d != dims; d++ {
+					overlaps = overlaps && key[d].Overlaps(query[d])
+					contains = contains && key[d].ContainsPoint(p[d])
+				}
+				if overlaps {
+					overlapping[key] = value
+				}
+				if contains {
+					containing[key] = value
+				}
+			}
+			checkBoxEntries(t, "Overlapping", b.Overlapping(query), overlapping)
+			checkBoxEntries(t, "ContainingPoint", b.ContainingPoint(p...),
+				containing)
+		}
+	}
+}
+
+// TestBoxTreeDims tests dimension checks.
+func TestBoxTreeDims(t *testing.T) {
+	expectPanic(t, "no dimensions", func() {
+		NewBoxTree(0)
+	})
+	b := NewBoxTree(2)
+	expectPanic(t, "insert box with wrong dimensions", func() {
+		b.ReplaceOrInsert([]Interval{{Int(0), Int(1)}}, 0)
+	})
+	expectPanic(t, "insert box with empty interval", func() {
+		b.ReplaceOrInsert([]Interval{{Int(0), Int(1)}, {Int(1), Int(1)}}, 0)
+	})
+	if b.root.Len() != 0 {
+		t.Error("failed insert modified the tree")
+	}
+	expectPanic(t, "point with wrong dimensions", func() {
+		b.ContainingPoint(Int(0), Int(1), Int(2))
+	})
+}
@@ -0,0 +1,363 @@
+package itree
+
+// Circular is an interval tree over a circular domain, such as angles, times
+// of day, or the ranges of a consistent-hash ring. The domain consists of the
+// points in [lo,hi), where hi is identified with lo.
+//
+// An interval [Left,Right) with Left.Less(Right) is an ordinary interval.
+// An interval with Right.Less(Left) wraps around past the origin and contains
+// the points in [Left,hi) and [lo,Right); e. g., [22,2) over the hours of a
+// day contains 22, 23, 0 and 1. An interval with equal endpoints contains the
+// whole domain. An endpoint equal to hi is treated as lo, so [22,24) is stored
+// as [22,0).
+//
+// Wrapping intervals are stored by their complement in a separate tree, so
+// all queries have the same complexity as their counterparts on T.
+// The zero value of Circular is not usable; use NewCircular.
+type Circular struct {
+	// lo and hi define the domain [lo,hi).
+	lo, hi Point
+
+	// linear contains the ordinary intervals.
+	linear T
+
+	// wrapping contains the wrapping intervals [a,b), stored as their
+	// complement [b,a). A full circle [x,x) is stored as the marker at x.
+	wrapping T
+}
+
+// NewCircular creates a new, empty circular interval tree over the domain
+// [lo,hi), which must not be empty.
+func NewCircular(lo, hi Point) *Circular {
+	if !lo.Less(hi) {
+		panic("empty domain")
+	}
+	return &Circular{
+		lo: lo,
+		hi: hi,
+	}
+}
+
+// point checks that the given point lies in the domain of this tree, or equals
+// its upper bound, and returns it with the upper bound replaced by the lower
+// bound.
+func (c *Circular) point(x Point) Point {
+	if x.Less(c.lo) || c.hi.Less(x) {
+		panic("point out of domain")
+	}
+	if equal(x, c.hi) {
+		return c.lo
+	}
+	return x
+}
+
+// normalize returns the given interval with both endpoints checked and
+// normalised by point.
+func (c *Circular) normalize(iv Interval) Interval {
+	return Interval{
+		Left:  c.point(iv.Left),
+		Right: c.point(iv.Right),
+	}
+}
+
+// key returns the tree storing the given normalised interval, and the interval
+// under which it is stored there.
+func (c *Circular) key(iv Interval) (*T, Interval) {
+	if iv.Left.Less(iv.Right) {
+		return &c.linear, iv
+	}
+	return &c.wrapping, Interval{iv.Right, iv.Left}
+}
+
+// wrappingEntry returns the entry for the given node of the wrapping tree.
+func wrappingEntry(n *Node) Entry {
+	return Entry{
+		Interval: Interval{n.interval.Right, n.interval.Left},
+		Value:    n.Value,
+	}
+}
+
+// Len returns the number of intervals in this tree.
+func (c *Circular) Len() int {
+	return c.linear.Len() + c.wrapping.Len()
+}
+
+// ReplaceOrInsert adds the given interval → value mapping to this tree. If the
+// interval already exists in the tree, the previous value is returned with
+// present == true. Otherwise, (nil, false) is returned.
+func (c *Circular) ReplaceOrInsert(iv Interval, value interface{}) (
+	previous interface{}, present bool,
+) {
+	t, k := c.key(c.normalize(iv))
+	found, parent, link := t.locate(k)
+	if found != nil {
+		previous = found.Value
+		t.SetValue(found, value)
+		return previous, true
+	}
+	t.linkNode(&Node{
+		Value:    value,
+		interval: k,
+	}, parent, link)
+	return nil, false
+}
+
+// Get retrieves the value for the given interval. If the interval is not part
+// of this tree, (nil, false) is returned. Otherwise, the value and
+// present == true is returned.
+func (c *Circular) Get(iv Interval) (value interface{}, present bool) {
+	t, k := c.key(c.normalize(iv))
+	found, _, _ := t.locate(k)
+	if found == nil {
+		return nil, false
+	}
+	return found.Value, true
+}
+
+// Delete deletes the given interval from this tree. If the interval is not
+// part of this tree, (nil, false) is returned. Otherwise, the value of the
+// deleted interval is returned with deleted == true.
+func (c *Circular) Delete(iv Interval) (value interface{}, deleted bool) {
+	t, k := c.key(c.normalize(iv))
+	found, _, _ := t.locate(k)
+	if found == nil {
+		return nil, false
+	}
+	value = found.Value
+	t.DeleteNode(found)
+	return value, true
+}
+
+// ContainingPoint returns all intervals containing the given point with their
+// values. The ordinary intervals come first, ordered by their intervals,
+// followed by the wrapping intervals, ordered by their right endpoints.
+// This operation runs in O((s+1)·log(n)) time, where s is the number of
+// returned intervals and n is the size of this tree.
+func (c *Circular) ContainingPoint(p Point) []Entry {
+	p = c.point(p)
+	var result []Entry
+	for _, n := range c.linear.NodesContainingPoint(p) {
+		result = append(result, Entry{n.interval, n.Value})
+	}
+	// A wrapping interval contains p unless its complement does.
+	for _, n := range c.wrapping.root.nodesOutside(nil, p, p, true) {
+		result = append(result, wrappingEntry(n))
+	}
+	return result
+}
+
+// Overlapping returns all intervals overlapping with the given interval, which
+// may wrap around, with their values, ordered like in ContainingPoint.
+// This operation runs in O((s+1)·log(n)) time, where s is the number of
+// returned intervals and n is the size of this tree.
+func (c *Circular) Overlapping(iv Interval) []Entry {
+	iv = c.normalize(iv)
+	var result []Entry
+	if iv.Left.Less(iv.Right) {
+		for _, n := range c.linear.NodesOverlappingInterval(iv) {
+			result = append(result, Entry{n.interval, n.Value})
+		}
+		for _, n := range c.wrapping.root.nodesOutside(
+			nil, iv.Left, iv.Right, false,
+		) {
+			result = append(result, wrappingEntry(n))
+		}
+		return result
+	}
+	// The interval wraps around, so it overlaps with all ordinary intervals
+	// starting before iv.Right or ending after iv.Left, and with all wrapping
+	// intervals, as they all contain the points just below hi.
+	n := c.linear.GetMin()
+	for ; n != nil && n.interval.Left.Less(iv.Right); n = n.Next() {
+		result = append(result, Entry{n.interval, n.Value})
+	}
+	for _, n := range c.linear.NodesOverlappingInterval(
+		Interval{iv.Left, c.hi},
+	) {
+		if !n.interval.Left.Less(iv.Right) {
+			result = append(result, Entry{n.interval, n.Value})
+		}
+	}
+	visitSubtree(c.wrapping.root, func(n *Node) {
+		result = append(result, wrappingEntry(n))
+	})
+	return result
+}
+
+// Gaps returns the maximal intervals of the domain not covered by any interval
+// in this tree, in ascending order of their right endpoints, so a gap wrapping
+// around past the origin comes first. If this tree is empty, the whole domain
+// [lo,lo) is returned. If the domain is covered completely, nil is returned.
+// This operation runs in O(n) time, where n is the size of this tree.
+func (c *Circular) Gaps() []Interval {
+	// The wrapping intervals cover [lo,start) and [end,hi).
+	start, end := c.lo, c.hi
+	if c.wrapping.root != nil {
+		if c.wrapping.root.markers {
+			return nil
+		}
+		start = c.wrapping.GetMax().interval.Left
+		end = c.wrapping.root.minRight
+		if !start.Less(end) {
+			return nil
+		}
+	}
+	var gaps []Interval
+	cursor := start
+	n := c.linear.GetMin()
+	for ; n != nil && n.interval.Left.Less(end); n = n.Next() {
+		if cursor.Less(n.interval.Left) {
+			gaps = append(gaps, Interval{cursor, n.interval.Left})
+		}
+		if cursor.Less(n.interval.Right) {
+			cursor = n.interval.Right
+		}
+	}
+	if cursor.Less(end) {
+		gaps = append(gaps, Interval{cursor, end})
+	}
+	if last := len(gaps) - 1; last > 0 && equal(gaps[0].Left, c.lo) &&
+		equal(gaps[last].Right, c.hi) {
+		gaps[0].Left = gaps[last].Left
+		gaps = gaps[:last]
+	}
+	for i := range gaps {
+		gaps[i] = c.normalize(gaps[i])
+	}
+	return gaps
+}
+
+// nodesEndingBefore appends all nodes in the subtree defined by this node
+// which end before the given point to the given list and returns it. If endAt
+// is true, nodes ending at the given point are included as well. A nil node is
+// permitted.
+func (n *Node) nodesEndingBefore(list []*Node, y Point, endAt bool) []*Node {
+	if n == nil || y.Less(n.minRight) || (!endAt && equal(n.minRight, y)) {
+		return list
+	}
+	n.push()
+	list = n.left.nodesEndingBefore(list, y, endAt)
+	if n.interval.Right.Less(y) || (endAt && equal(n.interval.Right, y)) {
+		list = append(list, n)
+	}
+	return n.right.nodesEndingBefore(list, y, endAt)
+}
+
+// nodesOutside appends all nodes in the subtree defined by this node which
+// start after x or end before y to the given list, in sort-order, and returns
+// it. If endAt is true, nodes ending at y are included as well. A nil node is
+// permitted.
+func (n *Node) nodesOutside(list []*Node, x, y Point, endAt bool) []*Node {
+	if n == nil {
+		return list
+	}
+	n.push()
+	if x.Less(n.interval.Left) {
+		list = n.left.nodesOutside(list, x, y, endAt)
+		list = append(list, n)
+		visitSubtree(n.right, func(m *Node) {
+			list = append(list, m)
+		})
+		return list
+	}
+	list = n.left.nodesEndingBefore(list, y, endAt)
+	if n.interval.Right.Less(y) || (endAt && equal(n.interval.Right, y)) {
+		list = append(list, n)
+	}
+	return n.right.nodesOutside(list, x, y, endAt)
+}
+
+// Ring assigns the keys of a circular domain, such as the hashes of a
+// consistent-hash ring, to members placed at positions on the ring. A member
+// at position x owns the arc [x,y) up to the position y of the next member,
+// wrapping around past the origin; a single member owns the whole domain.
+// The zero value of Ring is not usable; use NewRing.
+type Ring struct {
+	// arcs maps the arc of each member to the member.
+	arcs *Circular
+}
+
+// NewRing creates a new, empty ring over the circular domain [lo,hi) (see
+// NewCircular).
+func NewRing(lo, hi Point) *Ring {
+	return &Ring{
+		arcs: NewCircular(lo, hi),
+	}
+}
+
+// Len returns the number of members of this ring.
+func (r *Ring) Len() int {
+	return r.arcs.Len()
+}
+
+// arc returns the arc containing the given normalised point, and whether the
+// ring has any members.
+func (r *Ring) arc(pos Point) (Entry, bool) {
+	arcs := r.arcs.ContainingPoint(pos)
+	if len(arcs) == 0 {
+		return Entry{}, false
+	}
+	return arcs[0], true
+}
+
+// Add places the given member at the given position. If another member is
+// already placed there, it is replaced and returned with present == true.
+// Otherwise, (nil, false) is returned.
+// This operation runs in O(log(n)) time, where n is the size of this ring.
+func (r *Ring) Add(pos Point, member interface{}) (
+	previous interface{}, present bool,
+) {
+	pos = r.arcs.point(pos)
+	arc, ok := r.arc(pos)
+	switch {
+	case !ok:
+		r.arcs.ReplaceOrInsert(Interval{pos, pos}, member)
+		return nil, false
+	case equal(arc.Interval.Left, pos):
+		return r.arcs.ReplaceOrInsert(arc.Interval, member)
+	}
+	r.arcs.Delete(arc.Interval)
+	r.arcs.ReplaceOrInsert(Interval{arc.Interval.Left, pos}, arc.Value)
+	r.arcs.ReplaceOrInsert(Interval{pos, arc.Interval.Right}, member)
+	return nil, false
+}
+
+// Remove removes the member at the given position. Its arc is taken over by
+// the preceding member. If no member is placed at the given position,
+// (nil, false) is returned. Otherwise, the removed member is returned with
+// removed == true.
+// This operation runs in O(log(n)) time, where n is the size of this ring.
+func (r *Ring) Remove(pos Point) (member interface{}, removed bool) {
+	pos = r.arcs.point(pos)
+	arc, ok := r.arc(pos)
+	if !ok || !equal(arc.Interval.Left, pos) {
+		return nil, false
+	}
+	r.arcs.Delete(arc.Interval)
+	if r.arcs.Len() == 0 {
+		return arc.Value, true
+	}
+	// The preceding arc ends at pos. It is the ordinary arc ending there, if
+	// any, and otherwise the only wrapping arc.
+	var prev Entry
+	if e, nodes := r.arcs.linear.NextEnd(pos); e != nil && equal(e, pos) {
+		prev = Entry{nodes[0].interval, nodes[0].Value}
+	} else {
+		prev = wrappingEntry(r.arcs.wrapping.GetMin())
+	}
+	r.arcs.Delete(prev.Interval)
+	r.arcs.ReplaceOrInsert(Interval{prev.Interval.Left, arc.Interval.Right},
+		prev.Value)
+	return arc.Value, true
+}
+
+// Owner returns the member owning the given key, along with its position,
+// with ok == true. If this ring has no members, ok is false.
+// This operation runs in O(log(n)) time, where n is the size of this ring.
+func (r *Ring) Owner(key Point) (pos Point, member interface{}, ok bool) {
+	arc, ok := r.arc(r.arcs.point(key))
+	if !ok {
+		return nil, nil, false
+	}
+	return arc.Interval.Left, arc.Value, true
+}
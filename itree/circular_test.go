@@ -0,0 +1,189 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// circularContains checks whether the given interval over the circular domain
+// [0,24) contains the given point.
+func circularContains(iv Interval, p Int) bool {
+	left, right := iv.Left.(Int)%24, iv.Right.(Int)%24
+	if left < right {
+		return left <= p && p < right
+	}
+	return left <= p || p < right
+}
+
+// checkEntries checks that the given entries contain exactly the given
+// intervals, in any order. Errors are logged to t, prefixed with what.
+func checkEntries(t *testing.T, what string, entries []Entry,
+	expected map[Interval]bool) {
+	if len(entries) != len(expected) {
+		t.Errorf("%s: expected %d entries, got %d", what, len(expected),
+			len(entries))
+		return
+	}
+	for _, e := range entries {
+		if !expected[e.Interval] {
+			t.Errorf("%s: unexpected entry %v", what, e.Interval)
+		}
+	}
+}
+
+// TestCircular tests a few wrapping intervals over the hours of a day.
+func TestCircular(t *testing.T) {
+	c := NewCircular(Int(0), Int(24))
+	c.ReplaceOrInsert(Interval{Int(22), Int(2)}, "night")
+	c.ReplaceOrInsert(Interval{Int(9), Int(17)}, "work")
+	c.ReplaceOrInsert(Interval{Int(20), Int(24)}, "evening")
+	if v, ok := c.Get(Interval{Int(20), Int(0)}); !ok || v != "evening" {
+		t.Errorf("expected evening stored as [20,0), got %v", v)
+	}
+	checkEntries(t, "ContainingPoint(23)", c.ContainingPoint(Int(23)),
+		map[Interval]bool{
+			{Int(22), Int(2)}: true, {Int(20), Int(0)}: true,
+		})
+	checkEntries(t, "ContainingPoint(1)", c.ContainingPoint(Int(1)),
+		map[Interval]bool{{Int(22), Int(2)}: true})
+	checkEntries(t, "Overlapping([16,21))",
+		c.Overlapping(Interval{Int(16), Int(21)}),
+		map[Interval]bool{{Int(9), Int(17)}: true, {Int(20), Int(0)}: true})
+	gaps := c.Gaps()
+	if len(gaps) != 2 || !gaps[0].Equal(Interval{Int(2), Int(9)}) ||
+		!gaps[1].Equal(Interval{Int(17), Int(20)}) {
+		t.Errorf("unexpected gaps %v", gaps)
+	}
+	c.ReplaceOrInsert(Interval{Int(5), Int(5)}, "always")
+	if gaps := c.Gaps(); gaps != nil {
+		t.Errorf("unexpected gaps %v with full circle", gaps)
+	}
+	if _, deleted := c.Delete(Interval{Int(5), Int(5)}); !deleted {
+		t.Error("full circle not deleted")
+	}
+	c.Delete(Interval{Int(22), Int(2)})
+	c.Delete(Interval{Int(20), Int(0)})
+	gaps = c.Gaps()
+	if len(gaps) != 1 || !gaps[0].Equal(Interval{Int(17), Int(9)}) {
+		t.Errorf("expected wrapping gap [17,9), got %v", gaps)
+	}
+	expectPanic(t, "point out of domain", func() {
+		c.ContainingPoint(Int(25))
+	})
+	if gaps := NewCircular(Int(0), Int(24)).Gaps(); len(gaps) != 1 ||
+		!gaps[0].Equal(Interval{Int(0), Int(0)}) {
+		t.Errorf("expected full gap in empty tree, got %v", gaps)
+	}
+}
+
+// TestCircularRandom compares random circular trees with brute force.
+func TestCircularRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	for i := 0; i != 50; i++ {
+		c := NewCircular(Int(0), Int(24))
+		ivs := make(map[Interval]bool)
+		for j, size := 0, rand.Intn(20); j != size; j++ {
+			iv := Interval{Int(rand.Intn(24)), Int(rand.Intn(24))}
+			c.ReplaceOrInsert(iv, j)
+			ivs[iv] = true
+		}
+		if c.Len() != len(ivs) {
+			t.Errorf("expected %d intervals, got %d", len(ivs), c.Len())
+		}
+		covered := make(map[Int]bool)
+		for p := Int(0); p != 24; p++ {
+			expected := make(map[Interval]bool)
+			for iv := range ivs {
+				if circularContains(iv, p) {
+					expected[iv] = true
+					covered[p] = true
+				}
+			}
+			checkEntries(t, "ContainingPoint", c.ContainingPoint(p), expected)
+		}
+		for j := 0; j != 20; j++ {
+			q := Interval{Int(rand.Intn(24)), Int(rand.Intn(24))}
+			expected := make(map[Interval]bool)
+			for iv := range ivs {
+				for p := Int(0); p != 24; p++ {
+					if circularContains(iv, p) && circularContains(q, p) {
+						expected[iv] = true
+					}
+				}
+			}
+			checkEntries(t, "Overlapping", c.Overlapping(q), expected)
+		}
+		uncovered := make(map[Int]bool)
+		for _, gap := range c.Gaps() {
+			for p := Int(0); p != 24; p++ {
+				if circularContains(gap, p) {
+					if uncovered[p] {
+						t.Errorf("point %d in several gaps", p)
+					}
+					uncovered[p] = true
+				}
+			}
+		}
+		for p := Int(0); p != 24; p++ {
+			if covered[p] == uncovered[p] {
+				t.Errorf("point %d covered %t, in gap %t",
+					p, covered[p], uncovered[p])
+			}
+		}
+	}
+}
+
+// TestRing tests assigning keys to ring members.
+func TestRing(t *testing.T) {
+	seedOnce.Do(seedRand)
+	r := NewRing(Uint32(0), Uint32(1000))
+	if _, _, ok := r.Owner(Uint32(5)); ok {
+		t.Error("owner in empty ring")
+	}
+	members := make(map[Uint32]int)
+	check := func() {
+		if r.Len() != len(members) {
+			t.Errorf("expected %d members, got %d", len(members), r.Len())
+		}
+		for i := 0; i != 100; i++ {
+			key := Uint32(rand.Intn(1000))
+			// The owner is the member at the greatest position <= key, or
+			// the member at the greatest position overall if there is none.
+			var best Uint32
+			found := false
+			for pos := range members {
+				if pos <= key && (!found || pos > best) {
+					best, found = pos, true
+				}
+			}
+			for pos := range members {
+				if !found || (best > key && pos > best) {
+					best, found = pos, true
+				}
+			}
+			pos, member, ok := r.Owner(key)
+			if ok != found || (found && (pos != best || member != members[best])) {
+				t.Errorf("key %d: expected owner %d, got %v (%t)", key, best, pos, ok)
+			}
+		}
+	}
+	for i := 0; i != 50; i++ {
+		pos := Uint32(rand.Intn(1000))
+		previous, present := r.Add(pos, i)
+		if p, ok := members[pos]; ok != present || (ok && previous != p) {
+			t.Errorf("Add(%d): expected previous %v, got %v", pos, p, previous)
+		}
+		members[pos] = i
+		check()
+	}
+	for pos := range members {
+		if member, removed := r.Remove(pos); !removed || member != members[pos] {
+			t.Errorf("Remove(%d): expected %v, got %v", pos, members[pos], member)
+		}
+		delete(members, pos)
+		check()
+	}
+	if _, removed := r.Remove(Uint32(1)); removed {
+		t.Error("removed from empty ring")
+	}
+}
@@ -40,3 +40,43 @@ func seedRand() {
 	println("Seed:", seed)
 	rand.Seed(seed)
 }
+
+// checkValues checks that the values of the given nodes equal the expected
+// values, in order. Errors are logged to t, prefixed with what.
+func checkValues(t *testing.T, what string, nodes []*Node,
+	expected ...interface{}) {
+	if len(nodes) != len(expected) {
+		t.Errorf("%s: expected %d nodes, got %d", what, len(expected), len(nodes))
+		return
+	}
+	for i, n := range nodes {
+		if n.Value != expected[i] {
+			t.Errorf("%s: expected value %v at %d, got %v",
+				what, expected[i], i, n.Value)
+		}
+	}
+}
+
+// checkNodes checks that the given nodes equal the expected nodes, in order.
+// Errors are logged to t, prefixed with what.
+func checkNodes(t *testing.T, what string, nodes, expected []*Node) {
+	if len(nodes) != len(expected) {
+		t.Errorf("%s: expected %d nodes, got %d", what, len(expected), len(nodes))
+		return
+	}
+	for i, n := range nodes {
+		if n != expected[i] {
+			t.Errorf("%s: expected node %v at %d, got %v",
+				what, expected[i].interval, i, n.interval)
+		}
+	}
+}
+
+// reversed returns a reversed copy of the given list of nodes.
+func reversed(list []*Node) []*Node {
+	result := make([]*Node, len(list))
+	for i, n := range list {
+		result[len(list)-1-i] = n
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package itree
+
+// Covered returns the parts of the given interval covered by the intervals in
+// this tree, as a list of disjoint, non-adjacent intervals in ascending order.
+// Markers (see Marker) cover no length and are ignored.
+// This operation runs in O(k+log(n)) time, where k is the number of nodes
+// overlapping with iv, and n is the size of this tree.
+func (t *T) Covered(iv Interval) []Interval {
+	var result []Interval
+	for _, n := range t.NodesOverlappingInterval(iv) {
+		if n.interval.IsMarker() {
+			continue
+		}
+		covered := n.interval
+		if covered.Left.Less(iv.Left) {
+			covered.Left = iv.Left
+		}
+		if iv.Right.Less(covered.Right) {
+			covered.Right = iv.Right
+		}
+		// The nodes are ordered by their left endpoints, so covered can only
+		// overlap with or touch the last interval of the result.
+		if last := len(result) - 1; last >= 0 &&
+			lessOrEqual(covered.Left, result[last].Right) {
+			if result[last].Right.Less(covered.Right) {
+				result[last].Right = covered.Right
+			}
+			continue
+		}
+		result = append(result, covered)
+	}
+	return result
+}
+
+// Gaps returns the parts of the given interval not covered by any interval in
+// this tree, as a list of disjoint, non-adjacent intervals in ascending order.
+// This operation has the complexity of Covered.
+func (t *T) Gaps(iv Interval) []Interval {
+	var result []Interval
+	left := iv.Left
+	for _, covered := range t.Covered(iv) {
+		if left.Less(covered.Left) {
+			result = append(result, Interval{left, covered.Left})
+		}
+		left = covered.Right
+	}
+	if left.Less(iv.Right) {
+		result = append(result, Interval{left, iv.Right})
+	}
+	return result
+}
+
+// Coverage returns the total length (see Interval.Length) of the parts of the
+// given interval covered by the intervals in this tree. The points must
+// implement Metric.
+// This operation has the complexity of Covered.
+func (t *T) Coverage(iv Interval) float64 {
+	return totalLength(t.Covered(iv))
+}
+
+// GapLength returns the total length (see Interval.Length) of the parts of the
+// given interval not covered by any interval in this tree. The points must
+// implement Metric.
+// This operation has the complexity of Covered.
+func (t *T) GapLength(iv Interval) float64 {
+	return totalLength(t.Gaps(iv))
+}
+
+// totalLength returns the sum of the lengths of the given intervals.
+func totalLength(ivs []Interval) float64 {
+	sum := 0.0
+	for _, iv := range ivs {
+		sum += iv.Length()
+	}
+	return sum
+}
@@ -0,0 +1,85 @@
+package itree
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// TestCoverage tests covered parts and gaps of intervals.
+func TestCoverage(t *testing.T) {
+	var tree T
+	tree.ReplaceOrInsert(Interval{Int(0), Int(5)}, nil)
+	tree.ReplaceOrInsert(Interval{Int(2), Int(4)}, nil)
+	tree.ReplaceOrInsert(Interval{Int(5), Int(7)}, nil)
+	tree.ReplaceOrInsert(Interval{Int(10), Int(20)}, nil)
+	tree.ReplaceOrInsertMarker(Int(8), nil)
+	iv := Interval{Int(3), Int(15)}
+	expectIntervals(t, "Covered", tree.Covered(iv), []Interval{
+		{Int(3), Int(7)}, {Int(10), Int(15)},
+	})
+	expectIntervals(t, "Gaps", tree.Gaps(iv), []Interval{{Int(7), Int(10)}})
+	if c := tree.Coverage(iv); c != 9 {
+		t.Errorf("expected coverage 9, got %g", c)
+	}
+	if g := tree.GapLength(iv); g != 3 {
+		t.Errorf("expected gap length 3, got %g", g)
+	}
+	expectIntervals(t, "Gaps", tree.Gaps(Interval{Int(30), Int(40)}),
+		[]Interval{{Int(30), Int(40)}})
+	expectIntervals(t, "Covered", tree.Covered(Interval{Int(30), Int(40)}), nil)
+}
+
+// expectIntervals checks that the given intervals match the expected ones.
+// Errors are logged to t, prefixed with what.
+func expectIntervals(t *testing.T, what string, ivs, expected []Interval) {
+	if len(ivs) != len(expected) {
+		t.Errorf("%s: expected %v, got %v", what, expected, ivs)
+		return
+	}
+	for i := range ivs {
+		if !ivs[i].Equal(expected[i]) {
+			t.Errorf("%s: expected %v, got %v", what, expected, ivs)
+			return
+		}
+	}
+}
+
+// TestCoverageRandom compares the coverage of random trees of BigInt points
+// with the covered points counted one by one.
+func TestCoverageRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	// Offset all points by 2^100, beyond the range of machine integers.
+	offset := new(big.Int).Lsh(big.NewInt(1), 100)
+	point := func(x int64) BigInt {
+		return BigIntOf(new(big.Int).Add(offset, big.NewInt(x)))
+	}
+	for i := 0; i != 20; i++ {
+		var tree T
+		covered := make([]bool, 100)
+		for j, size := 0, rand.Intn(20); j != size; j++ {
+			left := rand.Intn(90)
+			right := left + 1 + rand.Intn(10)
+			tree.ReplaceOrInsert(Interval{point(int64(left)),
+				point(int64(right))}, nil)
+			for x := left; x != right; x++ {
+				covered[x] = true
+			}
+		}
+		left := rand.Intn(50)
+		right := left + 1 + rand.Intn(50)
+		expected := 0
+		for x := left; x != right; x++ {
+			if covered[x] {
+				expected++
+			}
+		}
+		iv := Interval{point(int64(left)), point(int64(right))}
+		if c := tree.Coverage(iv); c != float64(expected) {
+			t.Errorf("expected coverage %d, got %g", expected, c)
+		}
+		if g := tree.GapLength(iv); g != float64(right-left-expected) {
+			t.Errorf("expected gap length %d, got %g", right-left-expected, g)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package itree
+
+import (
+	"sort"
+)
+
+// SplitFunc returns the value of the given piece of the interval iv, which is
+// mapped to the given value (see CutAtFunc).
+type SplitFunc func(iv Interval, value interface{}, piece Interval) interface{}
+
+// CutAt cuts every interval in this tree which contains one of the given
+// points in its interior into pieces at these points. Each piece inherits the
+// value of the cut interval. A piece equal to an interval already in the tree,
+// including a piece of another interval cut earlier in sort-order, collides
+// with it: the piece is dropped, and the existing interval keeps its value.
+// The number of cut intervals and the dropped pieces with their values are
+// returned.
+// This operation runs in O(k·log(k)+(s+k)·log(n)) time, where k is the number
+// of points, s is the number of resulting pieces, and n is the size of this
+// tree.
+func (t *T) CutAt(points ...Point) (cut int, dropped []Entry) {
+	return t.CutAtFunc(nil, points...)
+}
+
+// CutAtFunc works like CutAt, except that the value of each piece is given by
+// split. If split is nil, each piece inherits the value of the cut interval.
+func (t *T) CutAtFunc(
+	split SplitFunc, points ...Point,
+) (cut int, dropped []Entry) {
+	if t.root == nil || len(points) == 0 {
+		return 0, nil
+	}
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Less(sorted[j])
+	})
+	unique := sorted[:1]
+	for _, p := range sorted[1:] {
+		if unique[len(unique)-1].Less(p) {
+			unique = append(unique, p)
+		}
+	}
+	var nodes []*Node
+	seen := make(map[*Node]bool)
+	for _, p := range unique {
+		for _, n := range t.root.nodesStraddling(nil, p, false, false) {
+			if !seen[n] {
+				seen[n] = true
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].interval.Less(nodes[j].interval)
+	})
+	for _, n := range nodes {
+		t.DeleteNode(n)
+	}
+	for _, n := range nodes {
+		iv, value := n.interval, n.Value
+		i := sort.Search(len(unique), func(i int) bool {
+			return iv.Left.Less(unique[i])
+		})
+		for left, reuse := iv.Left, true; ; i++ {
+			right := iv.Right
+			last := i == len(unique) || !unique[i].Less(iv.Right)
+			if !last {
+				right = unique[i]
+			}
+			piece := Interval{left, right}
+			pieceValue := value
+			if split != nil {
+				pieceValue = split(iv, value, piece)
+			}
+			// Reuse the cut node for the first piece which does not collide
+			node := &Node{Value: pieceValue, interval: piece}
+			if reuse {
+				node = n
+				node.interval, node.Value = piece, pieceValue
+			}
+			if t.insertNode(node) {
+				reuse = false
+			} else {
+				dropped = append(dropped, Entry{piece, pieceValue})
+			}
+			if last {
+				break
+			}
+			left = right
+		}
+	}
+	return len(nodes), dropped
+}
+
+// CutBy cuts the intervals in this tree at all endpoints of the intervals in
+// the given other tree, as with CutAt.
+func (t *T) CutBy(other *T) (cut int, dropped []Entry) {
+	return t.CutByFunc(other, nil)
+}
+
+// CutByFunc works like CutBy, except that the value of each piece is given by
+// split, as with CutAtFunc.
+func (t *T) CutByFunc(
+	other *T, split SplitFunc,
+) (cut int, dropped []Entry) {
+	points := make([]Point, 0, 2*other.Len())
+	for n := other.GetMin(); n != nil; n = n.Next() {
+		points = append(points, n.interval.Left, n.interval.Right)
+	}
+	return t.CutAtFunc(split, points...)
+}
@@ -0,0 +1,111 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestCutAt tests cutting intervals at given points.
+func TestCutAt(t *testing.T) {
+	var tree T
+	if cut, _ := tree.CutAt(Int(1)); cut != 0 {
+		t.Error("cut intervals in empty tree")
+	}
+	tree.ReplaceOrInsert(Interval{Int(0), Int(10)}, "a")
+	tree.ReplaceOrInsert(Interval{Int(2), Int(4)}, "b")
+	tree.ReplaceOrInsert(Interval{Int(10), Int(12)}, "c")
+	n := tree.GetMin()
+	cut, dropped := tree.CutAt(Int(5), Int(3), Int(10), Int(5))
+	if cut != 2 || len(dropped) != 0 {
+		t.Errorf("expected 2 cut intervals, got %d, dropped %v", cut, dropped)
+	}
+	testContents(t, &tree, map[Interval]interface{}{
+		{Int(0), Int(3)}:   "a",
+		{Int(3), Int(5)}:   "a",
+		{Int(5), Int(10)}:  "a",
+		{Int(2), Int(3)}:   "b",
+		{Int(3), Int(4)}:   "b",
+		{Int(10), Int(12)}: "c",
+	})
+	if tree.GetMin() != n {
+		t.Error("first piece does not reuse cut node")
+	}
+	var other T
+	other.ReplaceOrInsert(Interval{Int(1), Int(11)}, nil)
+	cut, dropped = tree.CutByFunc(&other, func(iv Interval, value interface{},
+		piece Interval) interface{} {
+		return value.(string) + "'"
+	})
+	if cut != 2 || len(dropped) != 0 {
+		t.Errorf("expected 2 cut intervals, got %d, dropped %v", cut, dropped)
+	}
+	testContents(t, &tree, map[Interval]interface{}{
+		{Int(0), Int(1)}:   "a'",
+		{Int(1), Int(3)}:   "a'",
+		{Int(3), Int(5)}:   "a",
+		{Int(5), Int(10)}:  "a",
+		{Int(2), Int(3)}:   "b",
+		{Int(3), Int(4)}:   "b",
+		{Int(10), Int(11)}: "c'",
+		{Int(11), Int(12)}: "c'",
+	})
+}
+
+// TestCutAtCollisions tests that colliding pieces are dropped.
+func TestCutAtCollisions(t *testing.T) {
+	var tree T
+	tree.ReplaceOrInsert(Interval{Int(0), Int(10)}, "a")
+	tree.ReplaceOrInsert(Interval{Int(0), Int(5)}, "b")
+	tree.ReplaceOrInsert(Interval{Int(3), Int(5)}, "c")
+	n := tree.GetNode(Interval{Int(0), Int(10)})
+	cut, dropped := tree.CutAt(Int(3), Int(5))
+	if cut != 2 {
+		t.Errorf("expected 2 cut intervals, got %d", cut)
+	}
+	testInvariants(t, &tree)
+	testContents(t, &tree, map[Interval]interface{}{
+		{Int(0), Int(3)}:  "b",
+		{Int(3), Int(5)}:  "c",
+		{Int(5), Int(10)}: "a",
+	})
+	if len(dropped) != 3 {
+		t.Fatalf("expected 3 dropped pieces, got %v", dropped)
+	}
+	for i, e := range []Entry{
+		{Interval{Int(3), Int(5)}, "b"},
+		{Interval{Int(0), Int(3)}, "a"},
+		{Interval{Int(3), Int(5)}, "a"},
+	} {
+		if !dropped[i].Interval.Equal(e.Interval) || dropped[i].Value != e.Value {
+			t.Errorf("dropped piece %d: expected %v, got %v", i, e, dropped[i])
+		}
+	}
+	if tree.GetNode(Interval{Int(5), Int(10)}) != n {
+		t.Error("first inserted piece does not reuse cut node")
+	}
+}
+
+// TestCutAtRandom cuts random trees at random points and checks that no
+// interval contains a cut point in its interior afterwards.
+func TestCutAtRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	for i := 0; i != 20; i++ {
+		var tree T
+		for j := 0; j != 200; j++ {
+			tree.ReplaceOrInsert(randomInterval(), j)
+		}
+		points := make([]Point, rand.Intn(20))
+		for j := range points {
+			points[j] = Float64(2 * rand.Float64())
+		}
+		tree.CutAt(points...)
+		testInvariants(t, &tree)
+		for _, p := range points {
+			for n := tree.GetMin(); n != nil; n = n.Next() {
+				if n.interval.Left.Less(p) && p.Less(n.interval.Right) {
+					t.Errorf("interval %v not cut at %v", n.interval, p)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package itree
+
+// nodeFilter describes a set of nodes to be deleted by deleteMatching.
+// The functions none and all are called for subtrees whose nodes have left
+// endpoints in [lo,hi], where lo or hi are nil if unbounded, and must be
+// conservative: if they cannot decide cheaply, they must return false.
+type nodeFilter struct {
+	// none reports whether no node in the subtree defined by the given node
+	// matches. It must not be nil.
+	none func(n *Node, lo, hi Point) bool
+
+	// all reports whether all nodes in the subtree defined by the given node
+	// match. It may be nil.
+	all func(n *Node, lo, hi Point) bool
+
+	// match reports whether the given node matches.
+	match func(n *Node) bool
+}
+
+// join2 joins the subtrees defined by the nodes l and r, whose black heights
+// are hl and hr, respectively, and returns the root of the resulting subtree
+// along with its black height. The intervals in l must be less than the
+// intervals in r. Any of l and r may be nil. The links of l and r to their
+// previous parents are discarded.
+// This operation runs in O(log(n)) time, where n is the size of the resulting
+// subtree.
+func (t *T) join2(l *Node, hl int, r *Node, hr int) (*Node, int) {
+	switch {
+	case r == nil:
+		if l != nil {
+			if l.red {
+				hl++
+			}
+			l.parent, l.red = nil, false
+		}
+		return l, hl
+	case l == nil:
+		if r.red {
+			hr++
+		}
+		r.parent, r.red = nil, false
+		return r, hr
+	}
+	r.parent, r.red = nil, false
+	scratch := t.derive(r)
+	k := scratch.GetMin()
+	scratch.DeleteNode(k)
+	return t.join3(l, hl, k, scratch.root, blackHeight(scratch.root))
+}
+
+// visitSubtree calls visit for each node in the subtree defined by the given
+// node, in sort-order. A nil node is permitted.
+func visitSubtree(n *Node, visit func(*Node)) {
+	if n == nil {
+		return
+	}
+	n.push()
+	visitSubtree(n.left, visit)
+	visit(n)
+	visitSubtree(n.right, visit)
+}
+
+// filterSubtree removes all nodes matching the given filter from the subtree
+// defined by the given node, whose black height is h and whose nodes have left
+// endpoints in [lo,hi] (see nodeFilter), calls visit for each removed node in
+// sort-order, and returns the root of the remaining subtree along with its
+// black height. A nil node is permitted.
+func (t *T) filterSubtree(
+	n *Node, h int, lo, hi Point, f nodeFilter, visit func(*Node),
+) (*Node, int) {
+	switch {
+	case n == nil:
+		return nil, 0
+	case f.none(n, lo, hi):
+		return n, h
+	case f.all != nil && f.all(n, lo, hi):
+		visitSubtree(n, visit)
+		return nil, 0
+	}
+	n.push()
+	if !n.red {
+		h--
+	}
+	left, lh := t.filterSubtree(n.left, h, lo, n.interval.Left, f, visit)
+	if f.match(n) {
+		visit(n)
+		right, rh := t.filterSubtree(n.right, h, n.interval.Left, hi, f, visit)
+		return t.join2(left, lh, right, rh)
+	}
+	right, rh := t.filterSubtree(n.right, h, n.interval.Left, hi, f, visit)
+	return t.join3(left, lh, n, right, rh)
+}
+
+// deleteMatching removes all nodes matching the given filter from this tree
+// and passes their intervals and values to the given function in sort-order.
+// Subtrees with no matching nodes are kept wholesale, subtrees with only
+// matching nodes are removed wholesale, and the remaining subtrees are
+// joined.
+func (t *T) deleteMatching(f nodeFilter, deleted func(Entry)) {
+	t.mods++
+	h := blackHeight(t.root)
+	t.root, _ = t.filterSubtree(t.root, h, nil, nil, f, func(n *Node) {
+		t.unindexEnd(n)
+		deleted(Entry{n.interval, n.Value})
+	})
+	t.length = 0
+	if t.root != nil {
+		t.root.parent, t.root.red = nil, false
+		t.length = t.root.size
+	}
+}
+
+// collectEntries returns a function appending entries to the given list.
+func collectEntries(list *[]Entry) func(Entry) {
+	return func(e Entry) {
+		*list = append(*list, e)
+	}
+}
+
+// overlapFilter returns a filter matching all nodes overlapping with the
+// given interval and satisfying the given predicate. If pred is nil, all
+// overlapping nodes match.
+func overlapFilter(iv Interval, pred func(*Node) bool) nodeFilter {
+	f := nodeFilter{
+		none: func(n *Node, lo, hi Point) bool {
+			return n.endsBefore(iv.Left) ||
+				(lo != nil && lessOrEqual(iv.Right, lo))
+		},
+		match: func(n *Node) bool {
+			return n.interval.Overlaps(iv) && (pred == nil || pred(n))
+		},
+	}
+	if pred == nil {
+		f.all = func(n *Node, lo, hi Point) bool {
+			return hi != nil && hi.Less(iv.Right) && iv.Left.Less(n.minRight)
+		}
+	}
+	return f
+}
+
+// DeleteOverlapping removes all nodes overlapping with the given interval from
+// this tree, and returns the removed intervals with their values in
+// sort-order (see Interval.Less).
+// Subtrees with no removed nodes are kept wholesale, and the remaining
+// subtrees are joined, so removing many nodes is considerably faster than
+// deleting them one by one.
+func (t *T) DeleteOverlapping(iv Interval) []Entry {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	var deleted []Entry
+	t.deleteMatching(overlapFilter(iv, nil), collectEntries(&deleted))
+	return deleted
+}
+
+// DeleteContainedIn removes all nodes contained in the given interval from
+// this tree, and returns the removed intervals with their values in
+// sort-order, like DeleteOverlapping.
+func (t *T) DeleteContainedIn(iv Interval) []Entry {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	var deleted []Entry
+	t.deleteMatching(nodeFilter{
+		none: func(n *Node, lo, hi Point) bool {
+			return n.endsBefore(iv.Left) || iv.Right.Less(n.minRight) ||
+				(lo != nil && lessOrEqual(iv.Right, lo)) ||
+				(hi != nil && hi.Less(iv.Left))
+		},
+		all: func(n *Node, lo, hi Point) bool {
+			return lo != nil && lessOrEqual(iv.Left, lo) && n.endsBefore(iv.Right)
+		},
+		match: func(n *Node) bool {
+			return iv.ContainsInterval(n.interval)
+		},
+	}, collectEntries(&deleted))
+	return deleted
+}
+
+// DeleteContainingPoint removes all nodes containing the given point from this
+// tree, and returns the removed intervals with their values in sort-order,
+// like DeleteOverlapping.
+func (t *T) DeleteContainingPoint(p Point) []Entry {
+	var deleted []Entry
+	t.deleteMatching(nodeFilter{
+		none: func(n *Node, lo, hi Point) bool {
+			return n.endsBefore(p) || (lo != nil && p.Less(lo))
+		},
+		all: func(n *Node, lo, hi Point) bool {
+			return hi != nil && lessOrEqual(hi, p) && p.Less(n.minRight)
+		},
+		match: func(n *Node) bool {
+			return n.interval.ContainsPoint(p)
+		},
+	}, collectEntries(&deleted))
+	return deleted
+}
+
+// DeleteWhere removes all nodes overlapping with the given interval for which
+// the given predicate returns true from this tree, and returns the removed
+// intervals with their values in sort-order, like DeleteOverlapping.
+// The predicate must not modify this tree.
+func (t *T) DeleteWhere(iv Interval, pred func(*Node) bool) []Entry {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	var deleted []Entry
+	t.deleteMatching(overlapFilter(iv, pred), collectEntries(&deleted))
+	return deleted
+}
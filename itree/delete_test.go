@@ -0,0 +1,103 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestDeleteMatching compares the bulk delete functions on random trees with
+// a linear search.
+func TestDeleteMatching(t *testing.T) {
+	seedOnce.Do(seedRand)
+	even := func(n *Node) bool {
+		return n.Value.(int)%2 == 0
+	}
+	testCases := []struct {
+		name   string
+		del    func(tree *T, iv Interval) []Entry
+		match  func(n *Node, iv Interval) bool
+		panics bool
+	}{
+		{
+			"DeleteOverlapping",
+			func(tree *T, iv Interval) []Entry {
+				return tree.DeleteOverlapping(iv)
+			},
+			func(n *Node, iv Interval) bool {
+				return n.interval.Overlaps(iv)
+			},
+			true,
+		},
+		{
+			"DeleteContainedIn",
+			func(tree *T, iv Interval) []Entry {
+				return tree.DeleteContainedIn(iv)
+			},
+			func(n *Node, iv Interval) bool {
+				return iv.ContainsInterval(n.interval)
+			},
+			true,
+		},
+		{
+			"DeleteContainingPoint",
+			func(tree *T, iv Interval) []Entry {
+				return tree.DeleteContainingPoint(iv.Left)
+			},
+			func(n *Node, iv Interval) bool {
+				return n.interval.ContainsPoint(iv.Left)
+			},
+			false,
+		},
+		{
+			"DeleteWhere",
+			func(tree *T, iv Interval) []Entry {
+				return tree.DeleteWhere(iv, even)
+			},
+			func(n *Node, iv Interval) bool {
+				return n.interval.Overlaps(iv) && even(n)
+			},
+			true,
+		},
+	}
+	for _, tc := range testCases {
+		if tc.panics {
+			expectPanic(t, tc.name+" empty interval", func() {
+				tc.del(&T{}, Interval{Int(0), Int(0)})
+			})
+		}
+		for i := 0; i != 30; i++ {
+			tree := NewWithMonoid(listMonoid{})
+			for j, size := 0, rand.Intn(500); j != size; j++ {
+				tree.ReplaceOrInsert(randomInterval(), j)
+			}
+			iv := randomInterval()
+			if rand.Intn(2) == 0 {
+				iv.Right = iv.Left.(Float64) + Float64(rand.Float64()/10)
+			}
+			checkMap := make(map[Interval]interface{})
+			var expected []Entry
+			for n := tree.GetMin(); n != nil; n = n.Next() {
+				if tc.match(n, iv) {
+					expected = append(expected, Entry{n.interval, n.Value})
+				} else {
+					checkMap[n.interval] = n.Value
+				}
+			}
+			deleted := tc.del(tree, iv)
+			if len(deleted) != len(expected) {
+				t.Errorf("%s(%v): expected %d deleted entries, got %d",
+					tc.name, iv, len(expected), len(deleted))
+			} else {
+				for j, e := range deleted {
+					if !e.Interval.Equal(expected[j].Interval) ||
+						e.Value != expected[j].Value {
+						t.Errorf("%s(%v): expected deleted entry %v, got %v",
+							tc.name, iv, expected[j], e)
+					}
+				}
+			}
+			testContents(t, tree, checkMap)
+			testAggregates(t, tree)
+		}
+	}
+}
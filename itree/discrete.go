@@ -0,0 +1,236 @@
+package itree
+
+// Discrete is implemented by points of a discrete domain, in which each point
+// has an immediate successor and predecessor, unless it is the greatest or
+// least point, respectively. The integer point types of this package implement
+// Discrete.
+//
+// In a discrete domain, a closed range [lo,hi] equals the half-open interval
+// [lo,succ(hi)), and a marker at x (see Marker) covers the same points as
+// [x,succ(x)).
+type Discrete interface {
+	Point
+
+	// Succ returns the least point greater than this point, with ok == true.
+	// If this point is the greatest point, (nil, false) is returned.
+	Succ() (succ Point, ok bool)
+
+	// Pred returns the greatest point less than this point, with ok == true.
+	// If this point is the least point, (nil, false) is returned.
+	Pred() (pred Point, ok bool)
+}
+
+// discrete returns the given point as Discrete. It panics if the point does
+// not implement Discrete.
+func discrete(x Point) Discrete {
+	d, ok := x.(Discrete)
+	if !ok {
+		panic("point not discrete")
+	}
+	return d
+}
+
+// Closed returns the half-open interval containing the same points as the
+// closed range [lo,hi]. The points must implement Discrete. If hi has no
+// successor, the range cannot be represented, and Closed panics. If hi is less
+// than lo, the returned interval is empty.
+func Closed(lo, hi Point) Interval {
+	succ, ok := discrete(hi).Succ()
+	if !ok {
+		panic("no successor")
+	}
+	return Interval{
+		Left:  lo,
+		Right: succ,
+	}
+}
+
+// Last returns the greatest point contained in this interval, which must be
+// non-empty or a marker, so that [Left,Last()] is the closed range of this
+// interval. The right endpoint must implement Discrete.
+func (iv Interval) Last() Point {
+	if iv.IsMarker() {
+		return iv.Left
+	}
+	pred, _ := discrete(iv.Right).Pred()
+	return pred
+}
+
+// Points calls visit for all points contained in this interval in ascending
+// order. If visit returns false, the enumeration stops and false is returned.
+// The points must implement Discrete. A marker contains only the point it
+// marks.
+func (iv Interval) Points(visit func(Point) bool) bool {
+	if iv.IsMarker() {
+		return visit(iv.Left)
+	}
+	for x := iv.Left; x.Less(iv.Right); {
+		if !visit(x) {
+			return false
+		}
+		succ, ok := discrete(x).Succ()
+		if !ok {
+			break
+		}
+		x = succ
+	}
+	return true
+}
+
+// span returns the interval covering the same points as this interval, using
+// half-open form where possible: a marker at a discrete point x with a
+// successor is converted to [x,succ(x)). Other intervals are returned
+// unchanged.
+func (iv Interval) span() Interval {
+	if !iv.IsMarker() {
+		return iv
+	}
+	if d, ok := iv.Left.(Discrete); ok {
+		if succ, ok := d.Succ(); ok {
+			iv.Right = succ
+		}
+	}
+	return iv
+}
+
+// Adjacent checks whether this interval and the given interval are disjoint,
+// and their union contains all points between them, i. e., the union has no
+// gap. For example, [1,5) and [5,9) are adjacent, and so are a marker at 5 and
+// [1,5). If the points implement Discrete, markers at 4 and 5 are adjacent as
+// well.
+func (iv Interval) Adjacent(other Interval) bool {
+	if iv.Overlaps(other) {
+		return false
+	}
+	a, b := iv.span(), other.span()
+	return equal(a.Right, b.Left) || equal(b.Right, a.Left)
+}
+
+// step implements Succ and Pred of the integer point types. It returns the
+// given neighbour of a point with ok == true if valid, i. e., if ok reports
+// that its computation has not wrapped around. Otherwise, (nil, false) is
+// returned.
+func step(neighbour Point, ok bool) (Point, bool) {
+	if !ok {
+		return nil, false
+	}
+	return neighbour, true
+}
+
+// Succ implements Discrete.
+func (x Int) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Int) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Int8) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Int8) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Int16) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Int16) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Int32) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Int32) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Int64) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Int64) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Uint) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Uint) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Uint8) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Uint8) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Uint16) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Uint16) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Uint32) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Uint32) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Uint64) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Uint64) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Succ implements Discrete.
+func (x Uintptr) Succ() (Point, bool) {
+	return step(x+1, x+1 > x)
+}
+
+// Pred implements Discrete.
+func (x Uintptr) Pred() (Point, bool) {
+	return step(x-1, x-1 < x)
+}
+
+// Discrete interface checks.
+var (
+	_ = []Discrete{
+		Int(0), Int8(0), Int16(0), Int32(0), Int64(0),
+		Uint(0), Uint8(0), Uint16(0), Uint32(0), Uint64(0), Uintptr(0),
+	}
+)
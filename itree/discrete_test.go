@@ -0,0 +1,156 @@
+package itree
+
+import (
+	"testing"
+)
+
+// TestSuccPred tests the successor and predecessor at the type bounds.
+func TestSuccPred(t *testing.T) {
+	if succ, ok := Int8(126).Succ(); !ok || succ != Int8(127) {
+		t.Errorf("expected successor 127, got %v, %t", succ, ok)
+	}
+	if _, ok := Int8(127).Succ(); ok {
+		t.Error("successor of greatest Int8")
+	}
+	if _, ok := Int8(-128).Pred(); ok {
+		t.Error("predecessor of least Int8")
+	}
+	if _, ok := Uint8(255).Succ(); ok {
+		t.Error("successor of greatest Uint8")
+	}
+	if pred, ok := Uint(1).Pred(); !ok || pred != Uint(0) {
+		t.Errorf("expected predecessor 0, got %v, %t", pred, ok)
+	}
+	if _, ok := Uint(0).Pred(); ok {
+		t.Error("predecessor of least Uint")
+	}
+}
+
+// TestClosed tests converting closed ranges to half-open intervals and back.
+func TestClosed(t *testing.T) {
+	iv := Closed(Int(1), Int(4))
+	if !iv.Equal(Interval{Int(1), Int(5)}) {
+		t.Errorf("expected [1,5), got %v", iv)
+	}
+	if last := iv.Last(); last != Int(4) {
+		t.Errorf("expected last point 4, got %v", last)
+	}
+	if last := Marker(Int(7)).Last(); last != Int(7) {
+		t.Errorf("expected last point 7 of marker, got %v", last)
+	}
+	if iv := Closed(Uint8(3), Uint8(3)); !iv.Equal(Interval{Uint8(3), Uint8(4)}) {
+		t.Errorf("expected [3,4), got %v", iv)
+	}
+	expectPanic(t, "closed range up to greatest point", func() {
+		Closed(Uint8(0), Uint8(255))
+	})
+	expectPanic(t, "closed range of non-discrete points", func() {
+		Closed(Float64(0), Float64(1))
+	})
+}
+
+// TestPoints tests enumerating the points of an interval.
+func TestPoints(t *testing.T) {
+	var points []Point
+	collect := func(x Point) bool {
+		points = append(points, x)
+		return len(points) < 4
+	}
+	if !(Interval{Int(2), Int(5)}).Points(collect) {
+		t.Error("enumeration stopped early")
+	}
+	if (Interval{Int(5), Int(10)}).Points(collect) {
+		t.Error("enumeration not stopped")
+	}
+	Marker(Int(0)).Points(collect)
+	expected := []Point{Int(2), Int(3), Int(4), Int(5), Int(0)}
+	if len(points) != len(expected) {
+		t.Fatalf("expected points %v, got %v", expected, points)
+	}
+	for i, x := range points {
+		if x != expected[i] {
+			t.Errorf("expected point %v at %d, got %v", expected[i], i, x)
+		}
+	}
+	var n int
+	(Interval{Uint8(250), Uint8(255)}).Points(func(Point) bool {
+		n++
+		return true
+	})
+	if n != 5 {
+		t.Errorf("expected 5 points up to the type bound, got %d", n)
+	}
+}
+
+// TestAdjacent tests the adjacency of intervals and markers.
+func TestAdjacent(t *testing.T) {
+	for _, c := range []struct {
+		a, b     Interval
+		adjacent bool
+	}{
+		{Interval{Int(1), Int(5)}, Interval{Int(5), Int(9)}, true},
+		{Interval{Int(5), Int(9)}, Interval{Int(1), Int(5)}, true},
+		{Interval{Int(1), Int(5)}, Interval{Int(6), Int(9)}, false},
+		{Interval{Int(1), Int(6)}, Interval{Int(5), Int(9)}, false},
+		{Interval{Int(1), Int(5)}, Marker(Int(5)), true},
+		{Marker(Int(4)), Marker(Int(5)), true},
+		{Marker(Int(4)), Marker(Int(6)), false},
+		{Marker(Int(5)), Interval{Int(5), Int(9)}, false},
+		{Marker(Int(4)), Interval{Int(5), Int(9)}, true},
+		{Interval{Float64(1), Float64(5)}, Marker(Float64(5)), true},
+		{Marker(Float64(4)), Interval{Float64(5), Float64(9)}, false},
+	} {
+		if adjacent := c.a.Adjacent(c.b); adjacent != c.adjacent {
+			t.Errorf("%v adjacent to %v: expected %t, got %t",
+				c.a, c.b, c.adjacent, adjacent)
+		}
+	}
+}
+
+// TestCompactDiscrete tests merging markers in a discrete domain.
+func TestCompactDiscrete(t *testing.T) {
+	equalValues := func(a, b interface{}) bool {
+		return a == b
+	}
+	var tree T
+	tree.ReplaceOrInsert(Interval{Int(0), Int(3)}, "a")
+	tree.ReplaceOrInsertMarker(Int(3), "a")
+	tree.ReplaceOrInsertMarker(Int(4), "a")
+	tree.ReplaceOrInsertMarker(Int(6), "a")
+	tree.ReplaceOrInsertMarker(Int(7), "b")
+	if removed := tree.Compact(equalValues, false); removed != 2 {
+		t.Errorf("expected 2 removed nodes, got %d", removed)
+	}
+	testInvariants(t, &tree)
+	expected := []Interval{{Int(0), Int(5)}, Marker(Int(6)), Marker(Int(7))}
+	n := tree.GetMin()
+	for _, iv := range expected {
+		if n == nil || !n.Interval().Equal(iv) {
+			t.Fatalf("expected interval %v after compaction", iv)
+		}
+		n = n.Next()
+	}
+	var floats T
+	floats.ReplaceOrInsert(Interval{Float64(0), Float64(3)}, "a")
+	floats.ReplaceOrInsertMarker(Float64(3), "a")
+	floats.ReplaceOrInsertMarker(Float64(1), "a")
+	floats.ReplaceOrInsertMarker(Float64(5), "a")
+	floats.ReplaceOrInsert(Interval{Float64(5), Float64(6)}, "a")
+	if removed := floats.Compact(equalValues, false); removed != 0 {
+		t.Errorf("merged continuous markers without mergeOverlapping")
+	}
+	if removed := floats.Compact(equalValues, true); removed != 2 {
+		t.Errorf("expected 2 removed nodes, got %d", removed)
+	}
+	testInvariants(t, &floats)
+	expected = []Interval{
+		{Float64(0), Float64(3)}, Marker(Float64(3)), {Float64(5), Float64(6)},
+	}
+	n = floats.GetMin()
+	for _, iv := range expected {
+		if n == nil || !n.Interval().Equal(iv) {
+			t.Fatalf("expected interval %v after compaction", iv)
+		}
+		n = n.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package itree
+
+// The nodes of a tree are ordered by their left endpoints, so the lowest right
+// endpoint after a point cannot be found by a single descent. If enabled with
+// SetEndIndex, NextEnd therefore uses a secondary index of the nodes ordered by
+// their right endpoints. The index is a tree of markers at Tuple{Right, Left}
+// of the indexed intervals, with the indexed nodes as values. It is maintained
+// by modifications of single nodes. Operations moving many nodes at once
+// (Shift, SplitAt and Join) discard it instead.
+
+// endKey returns the key of the given interval in the end index.
+func endKey(iv Interval) Tuple {
+	return Tuple{iv.Right, iv.Left}
+}
+
+// indexEnd adds the given node to the end index of this tree, if any.
+func (t *T) indexEnd(n *Node) {
+	if t.ends != nil {
+		t.ends.ReplaceOrInsertMarker(endKey(n.interval), n)
+	}
+}
+
+// unindexEnd removes the given node from the end index of this tree, if any.
+// The node interval must not have changed since the node was indexed.
+func (t *T) unindexEnd(n *Node) {
+	if t.ends != nil {
+		t.ends.DeleteMarker(endKey(n.interval))
+	}
+}
+
+// SetEndIndex enables or disables the index of the nodes of this tree by their
+// right endpoints, which speeds up NextEnd for trees with many long
+// intervals. While the index is enabled, every insertion and deletion also
+// updates the index, which takes another O(log(n)) time and allocates an
+// index entry per node. Shift, SplitAt and Join disable the index of the trees
+// they modify or create, so it must be enabled again if needed.
+// Enabling the index builds it in O(n·log(n)) time, where n is the size of
+// this tree, unless it is enabled already.
+func (t *T) SetEndIndex(enabled bool) {
+	switch {
+	case !enabled:
+		t.ends = nil
+	case t.ends == nil:
+		t.ends = &T{}
+		visitSubtree(t.root, func(n *Node) {
+			t.ends.ReplaceOrInsertMarker(endKey(n.interval), n)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package itree
+
+// EntryRef refers to the place of an interval in a tree, which may or may not
+// be occupied by a node (see T.Entry). An EntryRef remains valid after
+// OrInsert and Modify. After Remove or any other modification of the tree
+// which inserts or removes nodes or changes intervals, it becomes stale, and
+// all its methods panic. Changes of values (see T.SetValue and T.UpdateRange)
+// do not affect an EntryRef.
+type EntryRef struct {
+	// t is the tree.
+	t *T
+
+	// mods is the modification count of t (see T.mods) when this entry was
+	// valid.
+	mods int
+
+	// iv is the interval.
+	iv Interval
+
+	// node is the node with interval iv, or nil if there is no such node.
+	node *Node
+
+	// parent and link describe where a node with interval iv would have to be
+	// linked if node is nil (see T.linkNode).
+	parent *Node
+	link   **Node
+}
+
+// Entry searches this tree for the given interval and returns a reference to
+// its place in the tree, which can be used to inspect, insert, modify or
+// remove the node for the interval without searching the tree again.
+// Entry panics if iv is empty, including markers (see ReplaceOrInsertMarker,
+// GetMarker and DeleteMarker).
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) Entry(iv Interval) *EntryRef {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	node, parent, link := t.locate(iv)
+	return &EntryRef{
+		t:      t,
+		mods:   t.mods,
+		iv:     iv,
+		node:   node,
+		parent: parent,
+		link:   link,
+	}
+}
+
+// check panics if this entry is stale.
+func (e *EntryRef) check() {
+	if e.mods != e.t.mods {
+		panic("tree modified")
+	}
+}
+
+// Node returns the node for the interval of this entry, or nil if there is no
+// such node. Node panics if the entry is stale.
+func (e *EntryRef) Node() *Node {
+	e.check()
+	return e.node
+}
+
+// Value returns the value for the interval of this entry. If the tree does not
+// contain the interval, (nil, false) is returned. Value panics if the entry is
+// stale.
+func (e *EntryRef) Value() (value interface{}, present bool) {
+	e.check()
+	if e.node == nil {
+		return nil, false
+	}
+	e.node.flush()
+	return e.node.Value, true
+}
+
+// OrInsert inserts a node mapping the interval of this entry to the given
+// value if no such node exists, and returns the node for the interval.
+// OrInsert panics if the entry is stale.
+func (e *EntryRef) OrInsert(value interface{}) *Node {
+	e.check()
+	if e.node == nil {
+		e.node = &Node{
+			Value:    value,
+			interval: e.iv,
+		}
+		// Lazy updates pending at the parent were meant for the nodes already
+		// in the tree, so they must not reach the new node.
+		e.parent.flush()
+		e.t.linkNode(e.node, e.parent, e.link)
+		e.parent, e.link = nil, nil
+		e.mods = e.t.mods
+	}
+	return e.node
+}
+
+// Modify replaces the value of the node for the interval of this entry with
+// the result of f applied to the current value. If no such node exists,
+// Modify does nothing. The entry is returned to allow chaining, e. g.,
+// t.Entry(iv).Modify(f).OrInsert(value). The function f must not modify the
+// tree other than by changing values. Modify panics if the entry is stale,
+// before or after calling f.
+func (e *EntryRef) Modify(f func(value interface{}) interface{}) *EntryRef {
+	e.check()
+	if e.node != nil {
+		e.node.flush()
+		value := f(e.node.Value)
+		e.check()
+		e.t.SetValue(e.node, value)
+	}
+	return e
+}
+
+// Remove deletes the node for the interval of this entry from the tree. If no
+// such node exists, (nil, false) is returned. Otherwise, the value of the
+// deleted node is returned with deleted == true. Remove panics if the entry is
+// stale.
+func (e *EntryRef) Remove() (value interface{}, deleted bool) {
+	e.check()
+	if e.node == nil {
+		return nil, false
+	}
+	value = e.node.Value
+	e.t.DeleteNode(e.node)
+	e.node = nil
+	return value, true
+}
+
+// Update looks up the given interval and calls f with its current value and
+// whether it is present in this tree. If f returns keep == true, the interval
+// is mapped to the returned value, inserting it if necessary. Otherwise, the
+// interval is removed from the tree if present. The function f must not modify
+// the tree other than by changing values, or Update panics.
+// This operation searches the tree only once, so it runs in O(log(n)) time,
+// where n is the size of this tree.
+func (t *T) Update(
+	iv Interval, f func(old interface{}, present bool) (new interface{}, keep bool),
+) {
+	e := t.Entry(iv)
+	old, present := e.Value()
+	value, keep := f(old, present)
+	e.check()
+	switch {
+	case keep && present:
+		t.SetValue(e.node, value)
+	case keep:
+		e.OrInsert(value)
+	case present:
+		e.Remove()
+	}
+}
@@ -0,0 +1,145 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestEntry tests inserting, modifying and removing nodes through entries.
+func TestEntry(t *testing.T) {
+	var tree T
+	iv := Interval{Int(0), Int(1)}
+	expectPanic(t, "Entry empty interval", func() {
+		tree.Entry(Interval{Int(0), Int(0)})
+	})
+	increment := func(value interface{}) interface{} {
+		return value.(int) + 1
+	}
+	e := tree.Entry(iv)
+	if e.Node() != nil {
+		t.Error("non-nil node in empty tree")
+	}
+	if value, present := e.Value(); present || value != nil {
+		t.Errorf("got value (%v,%v) from empty tree", value, present)
+	}
+	n := e.Modify(increment).OrInsert(1)
+	if n == nil || n.Value != 1 || e.Node() != n {
+		t.Fatal("OrInsert failed to insert node")
+	}
+	if tree.Entry(iv).Modify(increment).OrInsert(1) != n {
+		t.Error("OrInsert on existing interval returned different node")
+	}
+	if v, _ := tree.Get(iv); v != 2 {
+		t.Errorf("expected value 2 after Modify, got %v", v)
+	}
+	testInvariants(t, &tree)
+	if value, deleted := tree.Entry(iv).Remove(); !deleted || value != 2 {
+		t.Errorf("expected Remove to return (2,true), got (%v,%v)", value, deleted)
+	}
+	if value, deleted := tree.Entry(iv).Remove(); deleted || value != nil {
+		t.Errorf("expected Remove to return (nil,false), got (%v,%v)",
+			value, deleted)
+	}
+	testInvariants(t, &tree)
+	e = tree.Entry(iv)
+	e.OrInsert(1)
+	if _, deleted := e.Remove(); !deleted {
+		t.Error("Remove after OrInsert failed")
+	}
+	expectPanic(t, "OrInsert after Remove", func() {
+		e.OrInsert(1)
+	})
+	e = tree.Entry(iv)
+	tree.ReplaceOrInsert(Interval{Int(1), Int(2)}, 0)
+	expectPanic(t, "OrInsert on stale entry", func() {
+		e.OrInsert(1)
+	})
+	expectPanic(t, "Update modifying tree", func() {
+		tree.Update(iv, func(interface{}, bool) (interface{}, bool) {
+			tree.ReplaceOrInsert(Interval{Int(2), Int(3)}, 0)
+			return 1, true
+		})
+	})
+	e = tree.Entry(Interval{Int(1), Int(2)})
+	expectPanic(t, "Modify deleting node", func() {
+		e.Modify(func(value interface{}) interface{} {
+			tree.Delete(Interval{Int(1), Int(2)})
+			return value
+		})
+	})
+	testContents(t, &tree, map[Interval]interface{}{
+		{Int(2), Int(3)}: 0,
+	})
+	e = tree.Entry(Interval{Int(2), Int(3)})
+	tree.ReplaceOrInsert(Interval{Int(3), Int(4)}, 0)
+	expectPanic(t, "Node on stale entry", func() {
+		e.Node()
+	})
+	expectPanic(t, "Value on stale entry", func() {
+		e.Value()
+	})
+}
+
+// TestEntryLazy tests that entries do not pass pending lazy updates to new
+// nodes.
+func TestEntryLazy(t *testing.T) {
+	tree := NewWithMonoid(sumMonoid{})
+	for i := 0; i != 10; i++ {
+		tree.ReplaceOrInsert(Interval{Int(2 * i), Int(2*i + 1)}, i)
+	}
+	iv := Interval{Int(7), Int(8)}
+	e := tree.Entry(iv)
+	tree.UpdateRange(nil, nil, affine{1, 5})
+	e.OrInsert(100)
+	if value, _ := tree.Get(iv); value != 100 {
+		t.Errorf("expected value 100 after OrInsert, got %v", value)
+	}
+	present := Interval{Int(8), Int(9)}
+	e = tree.Entry(present)
+	tree.UpdateRange(nil, nil, affine{1, 5})
+	if value, _ := e.Value(); value != 14 {
+		t.Errorf("expected value 14 after UpdateRange, got %v", value)
+	}
+	iv = Interval{Int(9), Int(10)}
+	tree.Update(iv, func(interface{}, bool) (interface{}, bool) {
+		tree.UpdateRange(nil, nil, affine{1, 5})
+		return 100, true
+	})
+	if value, _ := tree.Get(iv); value != 100 {
+		t.Errorf("expected value 100 after Update, got %v", value)
+	}
+	testInvariants(t, tree)
+	if sum := tree.Aggregate(); sum != 405 {
+		t.Errorf("expected sum 405, got %v", sum)
+	}
+}
+
+// TestUpdate tests Update with random counters.
+func TestUpdate(t *testing.T) {
+	seedOnce.Do(seedRand)
+	tree := NewWithMonoid(listMonoid{})
+	checkMap := make(map[Interval]interface{})
+	for i := 0; i != 2000; i++ {
+		left := Int(rand.Intn(50))
+		iv := Interval{left, left + 1 + Int(rand.Intn(5))}
+		tree.Update(iv, func(old interface{}, present bool) (interface{}, bool) {
+			if !present {
+				return 1, true
+			}
+			if old.(int) == 3 {
+				return nil, false
+			}
+			return old.(int) + 1, true
+		})
+		switch old := checkMap[iv]; {
+		case old == nil:
+			checkMap[iv] = 1
+		case old == 3:
+			delete(checkMap, iv)
+		default:
+			checkMap[iv] = old.(int) + 1
+		}
+	}
+	testContents(t, tree, checkMap)
+	testAggregates(t, tree)
+}
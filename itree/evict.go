@@ -0,0 +1,31 @@
+package itree
+
+// EvictEndingBefore removes all nodes whose interval ends at or before the
+// given point from this tree, and returns the removed intervals with their
+// values in sort-order (see Interval.Less). A marker is removed if it lies at
+// or before the given point, like any other interval ending there.
+// Subtrees with no remaining nodes are removed and subtrees with no removed
+// nodes are kept wholesale, and the remaining subtrees are joined, so this
+// operation is considerably faster than deleting the nodes one by one.
+func (t *T) EvictEndingBefore(p Point) []Entry {
+	var evicted []Entry
+	t.EvictEndingBeforeFunc(p, collectEntries(&evicted))
+	return evicted
+}
+
+// EvictEndingBeforeFunc works like EvictEndingBefore, except that the removed
+// intervals and their values are passed to the given function in sort-order
+// instead of being returned. The function must not access this tree.
+func (t *T) EvictEndingBeforeFunc(p Point, evicted func(Entry)) {
+	t.deleteMatching(nodeFilter{
+		none: func(n *Node, lo, hi Point) bool {
+			return p.Less(n.minRight)
+		},
+		all: func(n *Node, lo, hi Point) bool {
+			return lessOrEqual(n.maxRight, p)
+		},
+		match: func(n *Node) bool {
+			return lessOrEqual(n.interval.Right, p)
+		},
+	}, evicted)
+}
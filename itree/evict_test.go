@@ -0,0 +1,50 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestEvictEndingBefore tests evicting nodes from random trees.
+func TestEvictEndingBefore(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var empty T
+	if evicted := empty.EvictEndingBefore(Int(0)); len(evicted) != 0 {
+		t.Errorf("evicted %v from empty tree", evicted)
+	}
+	for i := 0; i != 50; i++ {
+		tree := NewWithMonoid(listMonoid{})
+		checkMap := make(map[Interval]interface{})
+		for j, size := 0, rand.Intn(500); j != size; j++ {
+			iv := randomInterval()
+			tree.ReplaceOrInsert(iv, j)
+			checkMap[iv] = j
+		}
+		p := Float64(2 * rand.Float64())
+		for _, x := range []Float64{p, p / 2, p + 0.5} {
+			tree.ReplaceOrInsertMarker(x, x)
+			checkMap[Marker(x)] = x
+		}
+		evicted := tree.EvictEndingBefore(p)
+		for j, e := range evicted {
+			if p.Less(e.Interval.Right) {
+				t.Errorf("evicted %v ending after %v", e.Interval, p)
+			}
+			if checkMap[e.Interval] != e.Value {
+				t.Errorf("evicted %v → %v, expected value %v",
+					e.Interval, e.Value, checkMap[e.Interval])
+			}
+			if j > 0 && !evicted[j-1].Interval.Less(e.Interval) {
+				t.Errorf("evicted intervals out of order")
+			}
+			delete(checkMap, e.Interval)
+		}
+		for iv := range checkMap {
+			if lessOrEqual(iv.Right, p) {
+				t.Errorf("interval %v not evicted", iv)
+			}
+		}
+		testContents(t, tree, checkMap)
+		testAggregates(t, tree)
+	}
+}
@@ -4,45 +4,99 @@ package itree
 type Interval struct {
 	// Left and Right define the left and right endpoints of this interval,
 	// respectively. For a non-empty interval, Left.Less(Right) holds.
-	// Only non-empty intervals can be inserted into a tree.
+	// Only non-empty intervals and markers (see Marker) can be inserted into a
+	// tree.
 	Left, Right Point
 }
 
+// Marker returns the zero-length interval [x,x). Such an interval is empty as
+// a set of points, but it can be inserted into a tree with
+// T.ReplaceOrInsertMarker to mark a position, e. g., a cursor or an
+// instantaneous event. In queries and in the interval predicates, a marker at x
+// behaves like the single point x: it contains x, it overlaps every interval
+// containing x, and it is contained in every such interval. In particular, a
+// marker at the left endpoint of a query interval is reported, whereas a
+// marker at the right endpoint is not.
+func Marker(x Point) Interval {
+	return Interval{
+		Left:  x,
+		Right: x,
+	}
+}
+
+// IsMarker reports whether this interval is a marker, i. e., whether its left
+// and right endpoints are equal.
+func (iv Interval) IsMarker() bool {
+	return equal(iv.Left, iv.Right)
+}
+
 // empty reports whether this interval is empty.
 func (iv Interval) empty() bool {
 	return lessOrEqual(iv.Right, iv.Left)
 }
 
+// endsBefore reports whether this interval contains no point p with x <= p.
+// A marker is treated as the single point it marks.
+func (iv Interval) endsBefore(x Point) bool {
+	if iv.Left.Less(iv.Right) {
+		return lessOrEqual(iv.Right, x)
+	}
+	return iv.Left.Less(x)
+}
+
 // Less checks whether this interval is less than the given interval,
 // by lexicographic ordering of the left and right endpoints.
 func (iv Interval) Less(than Interval) bool {
-	if iv.Left.Less(than.Left) {
-		return true
-	}
-	if than.Left.Less(iv.Left) {
-		return false
+	if c := compare(iv.Left, than.Left); c != 0 {
+		return c < 0
 	}
 	return iv.Right.Less(than.Right)
 }
 
+// compare compares this interval with the given interval by lexicographic
+// ordering of the left and right endpoints, like Less. It returns a negative
+// number, zero, or a positive number if this interval is less than, equal to,
+// or greater than the given interval, respectively.
+func (iv Interval) compare(with Interval) int {
+	if c := compare(iv.Left, with.Left); c != 0 {
+		return c
+	}
+	return compare(iv.Right, with.Right)
+}
+
 // Equal checks whether this interval is equal to the given interval.
 func (iv Interval) Equal(to Interval) bool {
 	return equal(iv.Left, to.Left) && equal(iv.Right, to.Right)
 }
 
 // ContainsPoint checks whether this interval contains the given point.
+// A marker contains only the point it marks.
 func (iv Interval) ContainsPoint(x Point) bool {
-	return x.Less(iv.Right) && lessOrEqual(iv.Left, x)
+	if !lessOrEqual(iv.Left, x) {
+		return false
+	}
+	return x.Less(iv.Right) || (iv.IsMarker() && equal(x, iv.Left))
 }
 
 // ContainsInterval checks whether this interval completely contains the
-// given other interval.
+// given other interval. If the other interval is a marker, this is the case
+// if and only if this interval contains the marked point.
 func (iv Interval) ContainsInterval(other Interval) bool {
+	if other.IsMarker() {
+		return iv.ContainsPoint(other.Left)
+	}
 	return lessOrEqual(iv.Left, other.Left) && lessOrEqual(other.Right, iv.Right)
 }
 
 // Overlaps checks whether the intersection of this interval with the given
-// interval contains at least one point.
+// interval contains at least one point. A marker overlaps an interval if and
+// only if the interval contains the marked point.
 func (iv Interval) Overlaps(with Interval) bool {
+	switch {
+	case iv.IsMarker():
+		return with.ContainsPoint(iv.Left)
+	case with.IsMarker():
+		return iv.ContainsPoint(with.Left)
+	}
 	return !(lessOrEqual(with.Right, iv.Left) || lessOrEqual(iv.Right, with.Left))
 }
@@ -1,6 +1,7 @@
 package itree
 
 import (
+	"math"
 	"testing"
 )
 
@@ -16,13 +17,58 @@ func blackDepth(n *Node) int {
 	return result
 }
 
+// pushAll propagates all pending lazy updates in the subtree rooted at n.
+func pushAll(n *Node) {
+	if n == nil {
+		return
+	}
+	n.push()
+	pushAll(n.left)
+	pushAll(n.right)
+}
+
 // testInvariants tests the tree invariants.
 func testInvariants(t *testing.T, tree *T) {
+	pushAll(tree.root)
 	bd := blackDepth(tree.root)
 	n := testSubtreeInvariants(t, tree.root, 0, bd, nil, nil)
 	if tree.length != n {
 		t.Errorf("tree expected size %d actual size %d", tree.length, n)
 	}
+	if tree.weight != nil {
+		testWeights(t, tree, tree.root)
+	}
+	if tree.ends != nil {
+		testEnds(t, tree)
+	}
+}
+
+// testEnds tests that the end index of the tree contains exactly its nodes.
+func testEnds(t *testing.T, tree *T) {
+	if tree.ends.Len() != tree.Len() {
+		t.Errorf("end index size %d, tree size %d", tree.ends.Len(), tree.Len())
+	}
+	for n := tree.GetMin(); n != nil; n = n.Next() {
+		e := tree.ends.GetMarker(endKey(n.interval))
+		if e == nil || e.Value != n {
+			t.Errorf("node %v missing from end index", n.interval)
+		}
+	}
+}
+
+// testWeights tests the weightSum values in the subtree rooted at n and
+// returns the total weight of the subtree.
+func testWeights(t *testing.T, tree *T, n *Node) float64 {
+	if n == nil {
+		return 0
+	}
+	sum := tree.weight(n.interval) + testWeights(t, tree, n.left) +
+		testWeights(t, tree, n.right)
+	if math.Abs(sum-n.weightSum) > 1e-9*math.Abs(sum) {
+		t.Errorf("node %v expected weightSum %g, have %g",
+			n.Value, sum, n.weightSum)
+	}
+	return sum
 }
 
 // testSubtreeInvariants tests the tree invariants for the subtree rooted at n.
@@ -61,9 +107,30 @@ func testSubtreeInvariants(
 		t.Errorf("node %v expected maxRight %v, have %v",
 			n.Value, expectedMaxRight, n.maxRight)
 	}
-	return 1 + testSubtreeInvariants(
+	expectedMinRight := n.interval.Right
+	if n.left != nil && n.left.minRight.Less(expectedMinRight) {
+		expectedMinRight = n.left.minRight
+	}
+	if n.right != nil && n.right.minRight.Less(expectedMinRight) {
+		expectedMinRight = n.right.minRight
+	}
+	if !equal(expectedMinRight, n.minRight) {
+		t.Errorf("node %v expected minRight %v, have %v",
+			n.Value, expectedMinRight, n.minRight)
+	}
+	expectedMarkers := n.interval.IsMarker() ||
+		(n.left != nil && n.left.markers) || (n.right != nil && n.right.markers)
+	if expectedMarkers != n.markers {
+		t.Errorf("node %v expected markers %t, have %t",
+			n.Value, expectedMarkers, n.markers)
+	}
+	size := 1 + testSubtreeInvariants(
 		t, n.left, currentBD, finalBD, start, n.interval.Left,
 	) + testSubtreeInvariants(
 		t, n.right, currentBD, finalBD, n.interval.Left, end,
 	)
+	if size != n.size {
+		t.Errorf("node %v expected size %d, have %d", n.Value, size, n.size)
+	}
+	return size
 }
@@ -0,0 +1,221 @@
+package itree
+
+import (
+	"errors"
+)
+
+// BytesEnd is a point following all Bytes points. It serves as the right
+// endpoint of Bytes intervals which are unbounded above, e. g., the interval of
+// all keys, [Bytes{},BytesEnd{}), as there is no greatest byte string.
+type BytesEnd struct{}
+
+// Less checks whether x < y, which is never the case. It panics if y is
+// neither Bytes nor BytesEnd.
+func (x BytesEnd) Less(y Point) bool {
+	return x.Compare(y) < 0
+}
+
+// Compare implements Comparer. It panics if y is neither Bytes nor BytesEnd.
+func (BytesEnd) Compare(y Point) int {
+	switch y.(type) {
+	case BytesEnd:
+		return 0
+	case Bytes:
+		return 1
+	}
+	panic("point not Bytes")
+}
+
+// String returns a symbol for the end of the key space.
+func (BytesEnd) String() string {
+	return "∞"
+}
+
+// PrefixInterval returns the interval of Bytes points containing exactly the
+// byte strings starting with the given prefix. If the prefix consists of 0xFF
+// bytes only, including the empty prefix, the interval is unbounded above and
+// ends with BytesEnd. The interval does not share memory with prefix.
+func PrefixInterval(prefix []byte) Interval {
+	end := len(prefix)
+	for end > 0 && prefix[end-1] == 0xFF {
+		end--
+	}
+	iv := Interval{
+		Left:  append(Bytes{}, prefix...),
+		Right: BytesEnd{},
+	}
+	if end > 0 {
+		right := append(Bytes(nil), prefix[:end]...)
+		right[end-1]++
+		iv.Right = right
+	}
+	return iv
+}
+
+// rank returns the number of nodes in the subtree defined by this node whose
+// left endpoint is less than p. A nil node is permitted.
+func (n *Node) rank(p Point) int {
+	count := 0
+	for n != nil {
+		n.push()
+		if n.interval.Left.Less(p) {
+			count++
+			if n.left != nil {
+				count += n.left.size
+			}
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return count
+}
+
+// KeyRange is the set of nodes of a tree whose left endpoints, the keys, lie in
+// a given range, e. g., the keys stored in a shard. It is obtained with
+// T.KeyRange and valid until the tree is modified.
+type KeyRange struct {
+	// t is the tree.
+	t *T
+
+	// lo and hi are the positions of the first node in and the first node after
+	// the range, respectively, in the sort-order of the tree.
+	lo, hi int
+}
+
+// KeyRange returns the nodes of this tree whose left endpoints lie in the
+// given interval. Keys stored as markers (see Marker) are counted like other
+// intervals.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) KeyRange(iv Interval) KeyRange {
+	lo := t.root.rank(iv.Left)
+	hi := t.root.rank(iv.Right)
+	if hi < lo {
+		hi = lo
+	}
+	return KeyRange{
+		t:  t,
+		lo: lo,
+		hi: hi,
+	}
+}
+
+// Len returns the number of nodes in this key range.
+func (r KeyRange) Len() int {
+	return r.hi - r.lo
+}
+
+// Nth returns the node at the given zero-based position i in this key range,
+// in sort-order. The position must satisfy 0 <= i < r.Len().
+// This operation runs in O(log(n)) time, where n is the size of the tree.
+func (r KeyRange) Nth(i int) *Node {
+	if i < 0 || i >= r.Len() {
+		panic("position out of range")
+	}
+	return r.t.root.nodeAt(r.lo + i)
+}
+
+// SplitPoint returns the median key of this key range, i. e., the key at which
+// the range can be split into two parts with equal numbers of nodes, give or
+// take one, where the key belongs to the upper part. If the range contains
+// fewer than two distinct keys, there is no useful split point, and
+// (nil, false) is returned. If the median key occurs in several nodes, the
+// split point is moved down to the first of them, or up past the last of them
+// if the former would leave the lower part empty.
+// This operation runs in O(k+log(n)) time, where k is the number of nodes
+// sharing the median key, and n is the size of the tree.
+func (r KeyRange) SplitPoint() (key Point, ok bool) {
+	if r.Len() < 2 {
+		return nil, false
+	}
+	median := r.Nth(r.Len() / 2)
+	key = median.interval.Left
+	if r.t.root.rank(key) > r.lo {
+		return key, true
+	}
+	// The lower part would be empty, as all nodes before the median share its
+	// key, so move up to the next key.
+	n := median
+	for i := r.Len() / 2; i != r.Len(); i, n = i+1, n.Next() {
+		if !equal(n.interval.Left, key) {
+			return n.interval.Left, true
+		}
+	}
+	return nil, false
+}
+
+// ErrShardOverlaps is returned when a shard range cannot be assigned because
+// it overlaps with the range of another shard.
+var ErrShardOverlaps = errors.New("shard range overlaps another shard")
+
+// Shards maps disjoint key ranges to shards, e. g., the Bytes ranges owned by
+// the servers of a sharded key-value store. The zero value is an empty shard
+// map ready to use.
+type Shards struct {
+	// tree maps the ranges to the shards. The ranges do not overlap.
+	tree T
+}
+
+// Len returns the number of shard ranges.
+func (s *Shards) Len() int {
+	return s.tree.Len()
+}
+
+// Assign assigns the given non-empty key range to the given shard. If the
+// range overlaps with the range of another shard, ErrShardOverlaps is
+// returned and the shard map is left unchanged.
+// This operation runs in O(log(n)) time, where n is the number of ranges.
+func (s *Shards) Assign(iv Interval, shard interface{}) error {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	overlaps := false
+	s.tree.DescendOverlappingInterval(iv, func(*Node) bool {
+		overlaps = true
+		return false
+	})
+	if overlaps {
+		return ErrShardOverlaps
+	}
+	s.tree.ReplaceOrInsert(iv, shard)
+	return nil
+}
+
+// Unassign removes the given key range, which must be the exact range of a
+// shard. If there is no such range, (nil, false) is returned. Otherwise, the
+// shard is returned with removed == true.
+func (s *Shards) Unassign(iv Interval) (shard interface{}, removed bool) {
+	return s.tree.Delete(iv)
+}
+
+// Lookup returns the range containing the given key, along with its shard,
+// with ok == true. If the key is not part of any range, ok is false.
+// This operation runs in O(log(n)) time, where n is the number of ranges.
+func (s *Shards) Lookup(key Point) (iv Interval, shard interface{}, ok bool) {
+	s.tree.DescendContainingPoint(key, func(n *Node) bool {
+		iv, shard, ok = n.interval, n.Value, true
+		return false
+	})
+	return iv, shard, ok
+}
+
+// Split splits the range containing the given key at the key, e. g., at a
+// split point obtained from KeyRange.SplitPoint, so that the lower part stays
+// with its shard and the upper part, starting at the key, is assigned to the
+// given shard. If the key is not part of any range, or if it is the start of
+// its range, the shard map is left unchanged and false is returned.
+// This operation runs in O(log(n)) time, where n is the number of ranges.
+func (s *Shards) Split(key Point, upper interface{}) bool {
+	var found *Node
+	s.tree.DescendContainingPoint(key, func(n *Node) bool {
+		found = n
+		return false
+	})
+	if found == nil || !found.interval.Left.Less(key) {
+		return false
+	}
+	right := found.interval.Right
+	_ = s.tree.SetInterval(found, Interval{found.interval.Left, key})
+	s.tree.ReplaceOrInsert(Interval{key, right}, upper)
+	return true
+}
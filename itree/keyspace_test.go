@@ -0,0 +1,169 @@
+package itree
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// TestPrefixInterval tests prefix intervals of byte strings.
+func TestPrefixInterval(t *testing.T) {
+	for _, c := range []struct {
+		prefix []byte
+		right  Point
+	}{
+		{nil, BytesEnd{}},
+		{[]byte{}, BytesEnd{}},
+		{[]byte("ab"), Bytes("ac")},
+		{[]byte{'a', 0xFF}, Bytes("b")},
+		{[]byte{0xFF, 0xFF}, BytesEnd{}},
+		{[]byte{0x01, 0xFE, 0xFF}, Bytes{0x01, 0xFF}},
+	} {
+		iv := PrefixInterval(c.prefix)
+		if !equal(iv.Left, Bytes(c.prefix)) || !equal(iv.Right, c.right) {
+			t.Errorf("%x: unexpected interval %v", c.prefix, iv)
+		}
+	}
+	prefix := []byte{'a', 0xFF}
+	iv := PrefixInterval(prefix)
+	for _, key := range [][]byte{
+		{'a'}, {'a', 0xFE, 0xFF}, {'a', 0xFF}, {'a', 0xFF, 0xFF, 0x00}, {'b'},
+	} {
+		if contains := iv.ContainsPoint(Bytes(key)); contains !=
+			bytes.HasPrefix(key, prefix) {
+			t.Errorf("%x: unexpected containment %t", key, contains)
+		}
+	}
+	if prefix[1] != 0xFF {
+		t.Error("prefix modified")
+	}
+	end := BytesEnd{}
+	if !Bytes("\xff\xff\xff").Less(end) || end.Less(Bytes(nil)) ||
+		end.Compare(end) != 0 {
+		t.Error("unexpected order")
+	}
+	expectPanic(t, "comparing BytesEnd with String", func() {
+		end.Less(String(""))
+	})
+}
+
+// TestKeyRange tests counting and splitting keys in a key range.
+func TestKeyRange(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	var keys []int
+	for i := 0; i != 500; i++ {
+		key := rand.Intn(1000)
+		if _, present := tree.ReplaceOrInsertMarker(Int(key), nil); !present {
+			keys = append(keys, key)
+		}
+	}
+	for i := 0; i != 100; i++ {
+		lo := rand.Intn(1100) - 50
+		hi := lo + rand.Intn(500)
+		r := tree.KeyRange(Interval{Int(lo), Int(hi)})
+		expected := 0
+		for _, key := range keys {
+			if key >= lo && key < hi {
+				expected++
+			}
+		}
+		if r.Len() != expected {
+			t.Fatalf("[%d,%d): expected %d keys, got %d", lo, hi, expected,
+				r.Len())
+		}
+		split, ok := r.SplitPoint()
+		if ok != (expected >= 2) {
+			t.Fatalf("[%d,%d): unexpected split result %t", lo, hi, ok)
+		}
+		if !ok {
+			continue
+		}
+		lower := tree.KeyRange(Interval{Int(lo), split})
+		upper := tree.KeyRange(Interval{split, Int(hi)})
+		if lower.Len() != expected/2 || upper.Len() != expected-expected/2 {
+			t.Errorf("[%d,%d) split at %v: %d vs. %d keys", lo, hi, split,
+				lower.Len(), upper.Len())
+		}
+		if !equal(upper.Nth(0).Interval().Left, split) {
+			t.Errorf("split point %v is not a key", split)
+		}
+	}
+	empty := tree.KeyRange(Interval{Int(10), Int(5)})
+	if empty.Len() != 0 {
+		t.Errorf("expected no keys, got %d", empty.Len())
+	}
+	expectPanic(t, "position out of range", func() {
+		empty.Nth(0)
+	})
+}
+
+// TestKeyRangeDuplicates tests split points with keys shared by several nodes.
+func TestKeyRangeDuplicates(t *testing.T) {
+	var tree T
+	for right := 1; right <= 5; right++ {
+		tree.ReplaceOrInsert(Interval{Int(0), Int(right)}, nil)
+	}
+	tree.ReplaceOrInsert(Interval{Int(3), Int(4)}, nil)
+	all := Interval{Int(0), Int(10)}
+	if split, ok := tree.KeyRange(all).SplitPoint(); !ok || split != Int(3) {
+		t.Errorf("expected split point 3, got %v, %t", split, ok)
+	}
+	tree.ReplaceOrInsert(Interval{Int(-1), Int(0)}, nil)
+	if split, ok := tree.KeyRange(all).SplitPoint(); !ok || split != Int(3) {
+		t.Errorf("expected split point 3, got %v, %t", split, ok)
+	}
+	tree.Delete(Interval{Int(3), Int(4)})
+	if split, ok := tree.KeyRange(all).SplitPoint(); ok {
+		t.Errorf("expected no split point, got %v", split)
+	}
+}
+
+// TestShards tests assigning, looking up and splitting shard ranges.
+func TestShards(t *testing.T) {
+	var shards Shards
+	if err := shards.Assign(PrefixInterval(nil), "all"); err != nil {
+		t.Fatal(err)
+	}
+	err := shards.Assign(PrefixInterval([]byte("a")), "a")
+	if err != ErrShardOverlaps {
+		t.Errorf("expected ErrShardOverlaps, got %v", err)
+	}
+	if !shards.Split(Bytes("m"), "upper") {
+		t.Error("split failed")
+	}
+	if shards.Split(Bytes("m"), "again") {
+		t.Error("split at range start succeeded")
+	}
+	testInvariants(t, &shards.tree)
+	for _, c := range []struct {
+		key   string
+		shard string
+	}{
+		{"", "all"},
+		{"lzzz", "all"},
+		{"m", "upper"},
+		{"\xff\xff", "upper"},
+	} {
+		iv, shard, ok := shards.Lookup(Bytes(c.key))
+		if !ok || shard != c.shard || !iv.ContainsPoint(Bytes(c.key)) {
+			t.Errorf("%q: unexpected shard %v, %v, %t", c.key, iv, shard, ok)
+		}
+	}
+	shard, removed := shards.Unassign(Interval{Bytes("m"), BytesEnd{}})
+	if !removed || shard != "upper" {
+		t.Errorf("unexpected removal result %v, %t", shard, removed)
+	}
+	if _, _, ok := shards.Lookup(Bytes("x")); ok {
+		t.Error("unexpected shard for unassigned key")
+	}
+	if shards.Split(Bytes("x"), "x") {
+		t.Error("split of unassigned key succeeded")
+	}
+	if err := shards.Assign(PrefixInterval([]byte("x")), "x"); err != nil {
+		t.Fatal(err)
+	}
+	if shards.Len() != 2 {
+		t.Errorf("expected 2 ranges, got %d", shards.Len())
+	}
+}
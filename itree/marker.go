@@ -0,0 +1,179 @@
+package itree
+
+// Stickiness defines how Shift treats interval endpoints located exactly at
+// the position of an insertion.
+type Stickiness int
+
+// Stickiness values.
+const (
+	// AlwaysGrows makes intervals grow when text is inserted at either of their
+	// edges: left endpoints stay in place, right endpoints move.
+	AlwaysGrows Stickiness = iota
+
+	// NeverGrows keeps intervals from growing when text is inserted at either of
+	// their edges: left endpoints move, right endpoints stay in place.
+	NeverGrows
+
+	// GrowsOnlyBefore makes intervals grow only when text is inserted at their
+	// left edge: both left and right endpoints stay in place.
+	GrowsOnlyBefore
+
+	// GrowsOnlyAfter makes intervals grow only when text is inserted at their
+	// right edge: both left and right endpoints move.
+	GrowsOnlyAfter
+)
+
+// moves reports whether left and right endpoints, respectively, located at
+// the position of an insertion move with the inserted text.
+func (s Stickiness) moves() (left, right bool) {
+	switch s {
+	case NeverGrows:
+		return true, false
+	case GrowsOnlyBefore:
+		return false, false
+	case GrowsOnlyAfter:
+		return true, true
+	default:
+		return false, true
+	}
+}
+
+// shiftPoint returns the new position of the endpoint x after an edit at the
+// given position (see T.Shift). If moves is true, an endpoint located at the
+// position of an insertion moves with the inserted text.
+func shiftPoint(x, at, delta Int, moves bool) Int {
+	switch {
+	case delta > 0:
+		if at < x || (moves && x == at) {
+			return x + delta
+		}
+		return x
+	case x <= at:
+		return x
+	case x < at-delta:
+		return at
+	default:
+		return x + delta
+	}
+}
+
+// NewMarkerTree creates a new, empty interval tree for tracking intervals
+// over a document, such as decorations, diagnostics or folds. Its points must
+// be of type Int. The given stickiness defines how Shift treats endpoints at
+// the position of an insertion. The zero value of T behaves like a marker tree
+// with stickiness AlwaysGrows.
+func NewMarkerTree(s Stickiness) *T {
+	return &T{
+		stickiness: s,
+	}
+}
+
+// Shift adjusts the intervals in this tree to an edit of the underlying
+// document at the given position. Shift only supports trees whose points are
+// all of type Int, and it panics if the points are of another type.
+//
+// If delta is positive, delta points have been inserted at position at.
+// Endpoints after at move by delta, and endpoints at at move depending on the
+// stickiness of the tree (see NewMarkerTree).
+//
+// If delta is negative, the points in [at,at-delta) have been deleted.
+// Endpoints in the deleted range collapse to at, and endpoints after the
+// deleted range move by delta. Intervals which become empty, or equal to
+// another interval in the tree, are removed. The removed intervals are
+// returned with their values, using their intervals before the edit.
+//
+// Markers (see Marker) move like left endpoints, so they stay markers. A
+// marker in the deleted range collapses to at and is removed only if the tree
+// already contains a marker there.
+//
+// Shifts of the endpoints of whole subtrees are recorded in the subtree root
+// and propagated to the descendants only when they are accessed, so only the k
+// nodes whose intervals contain the edit position are repositioned
+// individually, and this operation runs in O((k+1)·log(n)) time, where n is the
+// size of this tree. In the worst case, e. g., if all intervals contain the
+// edit position, this is O(n·log(n)). Nodes obtained before the edit must be
+// passed to Flush before use. The weight function and monoid of the tree, if
+// any, must not depend on the absolute position of intervals.
+func (t *T) Shift(at, delta Int) []Entry {
+	if delta == 0 || t.root == nil {
+		return nil
+	}
+	if _, ok := t.root.interval.Left.(Int); !ok {
+		panic("point not Int")
+	}
+	t.ends = nil
+	t.mods++
+	leftMoves, rightMoves := t.stickiness.moves()
+	var touched []*Node
+	if delta > 0 {
+		touched = t.root.nodesStraddling(nil, at, !leftMoves, rightMoves)
+		for _, n := range touched {
+			t.DeleteNode(n)
+		}
+		t.root.shiftFrom(t, at, leftMoves, delta)
+	} else {
+		end := at - delta
+		touched = t.root.nodesOverlappingInterval(nil, Interval{at, end})
+		for _, n := range touched {
+			t.DeleteNode(n)
+		}
+		t.root.shiftFrom(t, end, true, delta)
+	}
+	var collapsed []Entry
+	for _, n := range touched {
+		iv := n.interval
+		if iv.IsMarker() {
+			n.interval = Marker(shiftPoint(iv.Left.(Int), at, delta, leftMoves))
+		} else {
+			n.interval = Interval{
+				shiftPoint(iv.Left.(Int), at, delta, leftMoves),
+				shiftPoint(iv.Right.(Int), at, delta, rightMoves),
+			}
+		}
+		if (n.interval.empty() && !iv.IsMarker()) || !t.insertNode(n) {
+			collapsed = append(collapsed, Entry{iv, n.Value})
+		}
+	}
+	return collapsed
+}
+
+// ReplaceOrInsertMarker adds a marker at the given point (see Marker) with the
+// given value to this tree. If the marker already exists in the tree, the
+// previous value is returned with present == true. Otherwise, (nil, false) is
+// returned. Unlike ReplaceOrInsert, which rejects all empty intervals, this is
+// the way to insert zero-length intervals into a tree.
+func (t *T) ReplaceOrInsertMarker(x Point, value interface{}) (
+	previous interface{}, present bool,
+) {
+	found, parent, link := t.locate(Marker(x))
+	if found != nil {
+		previous = found.Value
+		t.SetValue(found, value)
+		return previous, true
+	}
+	t.linkNode(&Node{
+		Value:    value,
+		interval: Marker(x),
+	}, parent, link)
+	return nil, false
+}
+
+// GetMarker retrieves the node for the marker at the given point from this
+// tree. If no such node exists, nil is returned.
+func (t *T) GetMarker(x Point) *Node {
+	found, _, _ := t.locate(Marker(x))
+	return found
+}
+
+// DeleteMarker deletes the marker at the given point from this tree. If no
+// such marker exists, (nil, false) is returned. Otherwise, the value of the
+// deleted node is returned with deleted == true.
+func (t *T) DeleteMarker(x Point) (value interface{}, deleted bool) {
+	n := t.GetMarker(x)
+	if n == nil {
+		return nil, false
+	}
+	value = n.Value
+	t.DeleteNode(n)
+	return value, true
+}
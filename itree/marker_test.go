@@ -0,0 +1,252 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestShift tests shifting intervals for a few simple edits.
+func TestShift(t *testing.T) {
+	tree := NewMarkerTree(NeverGrows)
+	tree.ReplaceOrInsert(Interval{Int(0), Int(5)}, 0)
+	tree.ReplaceOrInsert(Interval{Int(5), Int(10)}, 1)
+	tree.ReplaceOrInsert(Interval{Int(12), Int(15)}, 2)
+	n := tree.GetMax()
+	if collapsed := tree.Shift(5, 3); len(collapsed) != 0 {
+		t.Errorf("unexpected collapsed intervals %v", collapsed)
+	}
+	testInvariants(t, tree)
+	for _, iv := range []Interval{
+		{Int(0), Int(5)}, {Int(8), Int(13)}, {Int(15), Int(18)},
+	} {
+		if tree.GetNode(iv) == nil {
+			t.Errorf("missing interval %v after insertion", iv)
+		}
+	}
+	tree.Flush(n)
+	if iv := n.Interval(); !iv.Equal(Interval{Int(15), Int(18)}) {
+		t.Errorf("flushed node has interval %v", iv)
+	}
+	collapsed := tree.Shift(7, -8)
+	testInvariants(t, tree)
+	if len(collapsed) != 1 || collapsed[0].Value != 1 {
+		t.Errorf("expected interval 1 to collapse, got %v", collapsed)
+	}
+	for _, iv := range []Interval{{Int(0), Int(5)}, {Int(7), Int(10)}} {
+		if tree.GetNode(iv) == nil {
+			t.Errorf("missing interval %v after deletion", iv)
+		}
+	}
+	var strings T
+	strings.ReplaceOrInsert(Interval{String("a"), String("b")}, nil)
+	expectPanic(t, "Shift of non-Int points", func() {
+		strings.Shift(0, 1)
+	})
+}
+
+// TestShiftRandom compares random edits of a marker tree with a map.
+func TestShiftRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	for _, s := range []Stickiness{
+		AlwaysGrows, NeverGrows, GrowsOnlyBefore, GrowsOnlyAfter,
+	} {
+		leftMoves, rightMoves := s.moves()
+		tree := NewMarkerTree(s)
+		checkMap := make(map[Interval]interface{})
+		for i := 0; i != 300; i++ {
+			left := Int(rand.Intn(1000))
+			iv := Interval{left, left + 1 + Int(rand.Intn(50))}
+			tree.ReplaceOrInsert(iv, i)
+			checkMap[iv] = i
+		}
+		for i := 0; i != 200; i++ {
+			at, delta := Int(rand.Intn(1000)), Int(rand.Intn(20)-10)
+			newMap := make(map[Interval]interface{})
+			var moved []Entry
+			for n := tree.GetMin(); n != nil; n = n.Next() {
+				iv := n.Interval()
+				newIV := Interval{
+					shiftPoint(iv.Left.(Int), at, delta, leftMoves),
+					shiftPoint(iv.Right.(Int), at, delta, rightMoves),
+				}
+				var touched bool
+				if delta > 0 {
+					touched = (iv.Left.(Int) < at || (iv.Left == at && !leftMoves)) &&
+						(iv.Right.(Int) > at || (iv.Right == at && rightMoves))
+				} else {
+					touched = iv.Overlaps(Interval{at, at - delta})
+				}
+				if !touched {
+					newMap[newIV] = n.Value
+				} else {
+					moved = append(moved, Entry{newIV, n.Value})
+				}
+			}
+			for _, e := range moved {
+				if _, ok := newMap[e.Interval]; !ok && !e.Interval.empty() {
+					newMap[e.Interval] = e.Value
+				}
+			}
+			tree.Shift(at, delta)
+			testInvariants(t, tree)
+			if tree.Len() != len(newMap) {
+				t.Fatalf("expected %d nodes after Shift(%d, %d), got %d",
+					len(newMap), at, delta, tree.Len())
+			}
+			for iv, value := range newMap {
+				if v, ok := tree.Get(iv); !ok || v != value {
+					t.Errorf("expected %v → %v after Shift(%d, %d), got %v",
+						iv, value, at, delta, v)
+				}
+			}
+		}
+	}
+}
+
+// TestMarkers tests inserting, querying and deleting zero-length markers.
+func TestMarkers(t *testing.T) {
+	var tree T
+	expectPanic(t, "insert empty interval", func() {
+		tree.ReplaceOrInsert(Marker(Int(5)), 0)
+	})
+	tree.ReplaceOrInsert(Interval{Int(0), Int(5)}, 0)
+	tree.ReplaceOrInsert(Interval{Int(5), Int(10)}, 1)
+	if _, present := tree.ReplaceOrInsertMarker(Int(5), 2); present {
+		t.Error("marker unexpectedly present")
+	}
+	if previous, present := tree.ReplaceOrInsertMarker(Int(5), 3); !present ||
+		previous != 2 {
+		t.Errorf("expected previous marker value 2, got %v, %t",
+			previous, present)
+	}
+	tree.ReplaceOrInsertMarker(Int(10), 4)
+	testInvariants(t, &tree)
+	if n := tree.GetMarker(Int(5)); n == nil || !n.Interval().IsMarker() {
+		t.Error("marker at 5 not found")
+	}
+	checkValues(t, "NodesContainingPoint(5)",
+		tree.NodesContainingPoint(Int(5)), 3, 1)
+	checkValues(t, "NodesContainingPoint(10)",
+		tree.NodesContainingPoint(Int(10)), 4)
+	checkValues(t, "NodesOverlappingInterval([5,10))",
+		tree.NodesOverlappingInterval(Interval{Int(5), Int(10)}), 3, 1)
+	checkValues(t, "NodesOverlappingInterval([0,5))",
+		tree.NodesOverlappingInterval(Interval{Int(0), Int(5)}), 0)
+	checkValues(t, "NodesContainedInInterval([5,10))",
+		tree.NodesContainedInInterval(Interval{Int(5), Int(10)}), 3, 1)
+	checkValues(t, "NodesContainingInterval([5,6))",
+		tree.NodesContainingInterval(Interval{Int(5), Int(6)}), 1)
+	if e, list := tree.NextEnd(Int(5)); !equal(e, Int(5)) {
+		t.Errorf("expected next end 5, got %v", e)
+	} else {
+		checkValues(t, "NextEnd(5)", list, 0, 3)
+	}
+	if evicted := tree.EvictEndingBefore(Int(5)); len(evicted) != 2 ||
+		evicted[0].Value != 0 || evicted[1].Value != 3 {
+		t.Errorf("expected interval 0 and marker 3 to be evicted, got %v",
+			evicted)
+	}
+	tree.ReplaceOrInsertMarker(Int(5), 3)
+	if value, deleted := tree.DeleteMarker(Int(5)); !deleted || value != 3 {
+		t.Errorf("expected deleted marker value 3, got %v, %t", value, deleted)
+	}
+	if _, deleted := tree.DeleteMarker(Int(5)); deleted {
+		t.Error("marker deleted twice")
+	}
+	testInvariants(t, &tree)
+	checkValues(t, "NodesContainingPoint(5) after delete",
+		tree.NodesContainingPoint(Int(5)), 1)
+}
+
+// TestMarkersRandom compares queries on a tree with markers against the
+// interval predicates.
+func TestMarkersRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	var all []*Node
+	for i := 0; i != 500; i++ {
+		left := Int(rand.Intn(100))
+		if rand.Intn(3) == 0 {
+			tree.ReplaceOrInsertMarker(left, i)
+		} else {
+			tree.ReplaceOrInsert(Interval{left, left + 1 + Int(rand.Intn(10))}, i)
+		}
+	}
+	testInvariants(t, &tree)
+	for n := tree.GetMin(); n != nil; n = n.Next() {
+		all = append(all, n)
+	}
+	filter := func(pred func(*Node) bool) []*Node {
+		var list []*Node
+		for _, n := range all {
+			if pred(n) {
+				list = append(list, n)
+			}
+		}
+		return list
+	}
+	descend := func(f func(func(*Node) bool)) []*Node {
+		var list []*Node
+		f(func(n *Node) bool {
+			list = append(list, n)
+			return true
+		})
+		return reversed(list)
+	}
+	for i := 0; i != 100; i++ {
+		left := Int(rand.Intn(100))
+		iv := Interval{left, left + 1 + Int(rand.Intn(10))}
+		overlapping := filter(func(n *Node) bool {
+			return iv.Overlaps(n.interval)
+		})
+		contained := filter(func(n *Node) bool {
+			return iv.ContainsInterval(n.interval)
+		})
+		point := filter(func(n *Node) bool {
+			return n.interval.ContainsPoint(iv.Left)
+		})
+		checkNodes(t, "NodesOverlappingInterval",
+			tree.NodesOverlappingInterval(iv), overlapping)
+		checkNodes(t, "DescendOverlappingInterval",
+			descend(func(visit func(*Node) bool) {
+				tree.DescendOverlappingInterval(iv, visit)
+			}), overlapping)
+		checkNodes(t, "NodesContainedInInterval",
+			tree.NodesContainedInInterval(iv), contained)
+		checkNodes(t, "DescendContainedInInterval",
+			descend(func(visit func(*Node) bool) {
+				tree.DescendContainedInInterval(iv, visit)
+			}), contained)
+		checkNodes(t, "NodesContainingPoint",
+			tree.NodesContainingPoint(iv.Left), point)
+		checkNodes(t, "DescendContainingPoint",
+			descend(func(visit func(*Node) bool) {
+				tree.DescendContainingPoint(iv.Left, visit)
+			}), point)
+	}
+}
+
+// TestShiftMarkers tests that markers move like left endpoints.
+func TestShiftMarkers(t *testing.T) {
+	tree := NewMarkerTree(AlwaysGrows)
+	tree.ReplaceOrInsertMarker(Int(5), 0)
+	tree.ReplaceOrInsertMarker(Int(8), 1)
+	tree.ReplaceOrInsertMarker(Int(12), 2)
+	tree.Shift(5, 2)
+	testInvariants(t, tree)
+	for _, x := range []Int{5, 10, 14} {
+		if tree.GetMarker(x) == nil {
+			t.Errorf("missing marker at %d after insertion", x)
+		}
+	}
+	collapsed := tree.Shift(4, -8)
+	testInvariants(t, tree)
+	if len(collapsed) != 1 || collapsed[0].Value != 1 {
+		t.Errorf("expected marker 1 to collapse, got %v", collapsed)
+	}
+	for _, x := range []Int{4, 6} {
+		if tree.GetMarker(x) == nil {
+			t.Errorf("missing marker at %d after deletion", x)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package itree
+
+// Metric is an optional interface for points with a notion of distance, such
+// as numbers. It allows measuring the length of intervals, e. g., to sample
+// intervals proportionally to their length (see LengthWeight) or to measure
+// how much of an interval is covered by a tree (see T.Coverage).
+type Metric interface {
+	Point
+
+	// Distance returns the signed distance from this point to the given point,
+	// i. e., y - x for a point x and the given point y.
+	Distance(to Point) float64
+}
+
+// metric returns the given point as Metric. It panics if the point does not
+// implement Metric.
+func metric(x Point) Metric {
+	m, ok := x.(Metric)
+	if !ok {
+		panic("point not metric")
+	}
+	return m
+}
+
+// Length returns the distance from the left to the right endpoint of this
+// interval. The left endpoint must implement Metric.
+func (iv Interval) Length() float64 {
+	return metric(iv.Left).Distance(iv.Right)
+}
+
+// LengthWeight is a weight function (see T.SetWeight) which weighs each
+// interval by its length, so that SampleWeighted picks a node with a
+// probability proportional to the length of its interval.
+func LengthWeight(iv Interval) float64 {
+	return iv.Length()
+}
+
+// Distance implements Metric. It panics if y is not Int.
+func (x Int) Distance(y Point) float64 {
+	return float64(y.(Int)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Int8.
+func (x Int8) Distance(y Point) float64 {
+	return float64(y.(Int8)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Int16.
+func (x Int16) Distance(y Point) float64 {
+	return float64(y.(Int16)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Int32.
+func (x Int32) Distance(y Point) float64 {
+	return float64(y.(Int32)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Int64.
+func (x Int64) Distance(y Point) float64 {
+	return float64(y.(Int64)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Uint.
+func (x Uint) Distance(y Point) float64 {
+	return float64(y.(Uint)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Uint8.
+func (x Uint8) Distance(y Point) float64 {
+	return float64(y.(Uint8)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Uint16.
+func (x Uint16) Distance(y Point) float64 {
+	return float64(y.(Uint16)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Uint32.
+func (x Uint32) Distance(y Point) float64 {
+	return float64(y.(Uint32)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Uint64.
+func (x Uint64) Distance(y Point) float64 {
+	return float64(y.(Uint64)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Uintptr.
+func (x Uintptr) Distance(y Point) float64 {
+	return float64(y.(Uintptr)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Float32.
+func (x Float32) Distance(y Point) float64 {
+	return float64(y.(Float32)) - float64(x)
+}
+
+// Distance implements Metric. It panics if y is not Float64.
+func (x Float64) Distance(y Point) float64 {
+	return float64(y.(Float64)) - float64(x)
+}
+
+// Metric interface checks.
+var (
+	_ = []Metric{
+		Int(0), Int8(0), Int16(0), Int32(0), Int64(0),
+		Uint(0), Uint8(0), Uint16(0), Uint32(0), Uint64(0), Uintptr(0),
+		Float32(0), Float64(0),
+	}
+)
@@ -0,0 +1,45 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestLength tests the length of intervals of metric points.
+func TestLength(t *testing.T) {
+	for _, c := range []struct {
+		iv     Interval
+		length float64
+	}{
+		{Interval{Int(-2), Int(5)}, 7},
+		{Interval{Uint8(3), Uint8(250)}, 247},
+		{Interval{Float64(0.5), Float64(2)}, 1.5},
+	} {
+		if length := c.iv.Length(); length != c.length {
+			t.Errorf("%v: expected length %g, got %g", c.iv, c.length, length)
+		}
+	}
+	if d := Uint(5).Distance(Uint(2)); d != -3 {
+		t.Errorf("expected distance -3, got %g", d)
+	}
+	expectPanic(t, "length of non-metric interval", func() {
+		Interval{String("a"), String("b")}.Length()
+	})
+}
+
+// TestLengthWeight tests sampling intervals proportionally to their length.
+func TestLengthWeight(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	tree.SetWeight(LengthWeight)
+	tree.ReplaceOrInsert(Interval{Int(0), Int(1)}, "short")
+	tree.ReplaceOrInsert(Interval{Int(1), Int(100)}, "long")
+	testInvariants(t, &tree)
+	counts := make(map[interface{}]int)
+	for i := 0; i != 1000; i++ {
+		counts[tree.SampleWeighted(rand.New(rand.NewSource(int64(i))), nil).Value]++
+	}
+	if counts["short"] > 50 {
+		t.Errorf("short interval sampled %d times out of 1000", counts["short"])
+	}
+}
@@ -3,6 +3,9 @@ package itree
 // Node represents an element of an interval tree.
 type Node struct {
 	// Value is the node value. The tree structure is independent of the value.
+	// If the tree has been created with NewWithMonoid, the value must be changed
+	// with T.SetValue to keep the subtree aggregates up to date, and lazy range
+	// updates may be pending for nodes obtained earlier (see T.Flush).
 	Value interface{}
 
 	// interval is the interval which is mapped to Value by this node.
@@ -12,6 +15,32 @@ type Node struct {
 	// i. e., maxRight = max(interval.Right, left.maxRight, right.maxRight).
 	maxRight Point
 
+	// minRight is the minimal right endpoint in the subtree defined by this node,
+	// i. e., minRight = min(interval.Right, left.minRight, right.minRight).
+	minRight Point
+
+	// markers indicates whether the subtree defined by this node contains a
+	// marker (see Marker). Such a subtree cannot be pruned by maxRight alone, as
+	// a marker ending at a point still contains that point.
+	markers bool
+
+	// size is the number of nodes in the subtree defined by this node.
+	size int
+
+	// weightSum is the total weight of the nodes in the subtree defined by this
+	// node, as given by the weight function of the tree (see T.SetWeight).
+	// If the tree has no weight function, weightSum is zero.
+	weightSum float64
+
+	// agg is the aggregate of the nodes in the subtree defined by this node,
+	// as given by the monoid of the tree (see NewWithMonoid). If the tree has no
+	// monoid, agg is nil.
+	agg interface{}
+
+	// pending is a lazy update which has been applied to this node but not yet
+	// to its children (see T.UpdateRange and T.Shift), or nil.
+	pending *lazyUpdate
+
 	// parent, left, and right are the parent node and the left and right child,
 	// respectively, of this node. May be nil. The parent is nil only if this is
 	// the root node of the tree. The subtree defined by left only contains
@@ -29,6 +58,91 @@ type Node struct {
 	red bool
 }
 
+// lazyUpdate is a pending lazy update.
+type lazyUpdate struct {
+	// m is the monoid of the tree, or nil if no value update is pending.
+	m LazyMonoid
+
+	// delta is the pending value update.
+	delta interface{}
+
+	// shift is the pending shift of all interval endpoints (see T.Shift).
+	shift Int
+}
+
+// apply applies the given update to the value and aggregate of this node, and
+// lazily to its descendants.
+func (n *Node) apply(m LazyMonoid, delta interface{}) {
+	n.Value = m.ApplyValue(n.Value, delta)
+	n.agg = m.ApplyAggregate(n.agg, delta, n.size)
+	if n.left == nil && n.right == nil {
+		return
+	}
+	switch {
+	case n.pending == nil:
+		n.pending = &lazyUpdate{
+			m:     m,
+			delta: delta,
+		}
+	case n.pending.m == nil:
+		n.pending.m = m
+		n.pending.delta = delta
+	default:
+		n.pending.delta = m.Compose(n.pending.delta, delta)
+	}
+}
+
+// applyShift shifts all endpoints of this node by the given distance, and
+// lazily those of its descendants. The endpoints must be of type Int.
+func (n *Node) applyShift(d Int) {
+	n.interval.Left = n.interval.Left.(Int) + d
+	n.interval.Right = n.interval.Right.(Int) + d
+	n.maxRight = n.maxRight.(Int) + d
+	n.minRight = n.minRight.(Int) + d
+	if n.left == nil && n.right == nil {
+		return
+	}
+	if n.pending == nil {
+		n.pending = &lazyUpdate{
+			shift: d,
+		}
+	} else {
+		n.pending.shift += d
+	}
+}
+
+// push propagates the pending lazy update of this node, if any, to its
+// children. A nil node is permitted.
+func (n *Node) push() {
+	if n == nil || n.pending == nil {
+		return
+	}
+	for _, child := range [...]*Node{n.left, n.right} {
+		if child == nil {
+			continue
+		}
+		if n.pending.m != nil {
+			child.apply(n.pending.m, n.pending.delta)
+		}
+		if n.pending.shift != 0 {
+			child.applyShift(n.pending.shift)
+		}
+	}
+	n.pending = nil
+}
+
+// flush propagates all pending lazy updates of the ancestors of this node down
+// to this node and its children.
+func (n *Node) flush() {
+	var path []*Node
+	for current := n; current != nil; current = current.parent {
+		path = append(path, current)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].push()
+	}
+}
+
 // black reports whether the given node is black. Nil nodes are considered
 // to be black.
 func (n *Node) black() bool {
@@ -56,56 +170,54 @@ func (n *Node) sibling(parent *Node) *Node {
 func (n *Node) replaceOrInsert(t *T, interval Interval, value interface{}) (
 	previous interface{}, present bool,
 ) {
-	if n.maxRight.Less(interval.Right) {
-		n.maxRight = interval.Right
-	}
-	switch {
-	case n.interval.Less(interval):
+	n.push()
+	switch c := interval.compare(n.interval); {
+	case c > 0:
 		if n.right != nil {
 			return n.right.replaceOrInsert(t, interval, value)
 		}
-		n.right = &Node{
+		t.linkNode(&Node{
 			Value:    value,
 			interval: interval,
-			maxRight: interval.Right,
-			parent:   n,
-			red:      true,
-		}
-		t.rebalanceRed(n.right)
-		t.length++
+		}, n, &n.right)
 		return nil, false
-	case interval.Less(n.interval):
+	case c < 0:
 		if n.left != nil {
 			return n.left.replaceOrInsert(t, interval, value)
 		}
-		n.left = &Node{
+		t.linkNode(&Node{
 			Value:    value,
 			interval: interval,
-			maxRight: interval.Right,
-			parent:   n,
-			red:      true,
-		}
-		t.rebalanceRed(n.left)
-		t.length++
+		}, n, &n.left)
 		return nil, false
 	default:
 		previous = n.Value
-		n.Value = value
+		t.SetValue(n, value)
 		return previous, true
 	}
 }
 
+// endsBefore reports whether no node in the subtree defined by this node
+// contains a point p with x <= p (see Interval.endsBefore).
+func (n *Node) endsBefore(x Point) bool {
+	if n.markers {
+		return n.maxRight.Less(x)
+	}
+	return lessOrEqual(n.maxRight, x)
+}
+
 // nodesContainingPoint appends all nodes in the subtree defined by this node
 // whose interval contains the given point to the given list and returns it.
 func (n *Node) nodesContainingPoint(list []*Node, p Point) []*Node {
-	if lessOrEqual(n.maxRight, p) {
+	if n.endsBefore(p) {
 		return list
 	}
+	n.push()
 	if n.left != nil {
 		list = n.left.nodesContainingPoint(list, p)
 	}
 	if lessOrEqual(n.interval.Left, p) {
-		if p.Less(n.interval.Right) {
+		if !n.interval.endsBefore(p) {
 			list = append(list, n)
 		}
 		if n.right != nil {
@@ -121,6 +233,7 @@ func (n *Node) nodesContainingInterval(list []*Node, iv Interval) []*Node {
 	if n.maxRight.Less(iv.Right) {
 		return list
 	}
+	n.push()
 	if n.left != nil {
 		list = n.left.nodesContainingInterval(list, iv)
 	}
@@ -139,14 +252,15 @@ func (n *Node) nodesContainingInterval(list []*Node, iv Interval) []*Node {
 // node whose interval is contained in the given interval to the given list and
 // returns it.
 func (n *Node) nodesContainedInInterval(list []*Node, iv Interval) []*Node {
-	if lessOrEqual(n.maxRight, iv.Left) {
+	if n.endsBefore(iv.Left) {
 		return list
 	}
+	n.push()
 	if lessOrEqual(iv.Left, n.interval.Left) {
 		if n.left != nil {
 			list = n.left.nodesContainedInInterval(list, iv)
 		}
-		if lessOrEqual(n.interval.Right, iv.Right) {
+		if n.interval.endsBefore(iv.Right) {
 			list = append(list, n)
 		}
 	}
@@ -160,32 +274,345 @@ func (n *Node) nodesContainedInInterval(list []*Node, iv Interval) []*Node {
 // node whose interval has a non-empty intersection with the given interval
 // to the given list and returns it.
 func (n *Node) nodesOverlappingInterval(list []*Node, iv Interval) []*Node {
-	if lessOrEqual(n.maxRight, iv.Left) {
+	if n.endsBefore(iv.Left) {
 		return list
 	}
-	if iv.Left.Less(n.interval.Right) {
-		if n.left != nil {
-			list = n.left.nodesOverlappingInterval(list, iv)
+	n.push()
+	if n.left != nil {
+		list = n.left.nodesOverlappingInterval(list, iv)
+	}
+	if n.interval.Left.Less(iv.Right) {
+		if !n.interval.endsBefore(iv.Left) {
+			list = append(list, n)
+		}
+		if n.right != nil {
+			list = n.right.nodesOverlappingInterval(list, iv)
+		}
+	}
+	return list
+}
+
+// descendContainingPoint calls visit for all nodes in the subtree defined by
+// this node whose interval contains the given point, in descending order.
+// If visit returns false, the traversal stops and false is returned.
+func (n *Node) descendContainingPoint(p Point, visit func(*Node) bool) bool {
+	if n.endsBefore(p) {
+		return true
+	}
+	n.push()
+	if lessOrEqual(n.interval.Left, p) {
+		if n.right != nil && !n.right.descendContainingPoint(p, visit) {
+			return false
+		}
+		if !n.interval.endsBefore(p) && !visit(n) {
+			return false
 		}
-		if n.interval.Left.Less(iv.Right) {
+	}
+	if n.left != nil {
+		return n.left.descendContainingPoint(p, visit)
+	}
+	return true
+}
+
+// descendContainingInterval calls visit for all nodes in the subtree defined
+// by this node whose interval contains the given interval, in descending
+// order. If visit returns false, the traversal stops and false is returned.
+func (n *Node) descendContainingInterval(
+	iv Interval, visit func(*Node) bool,
+) bool {
+	if n.maxRight.Less(iv.Right) {
+		return true
+	}
+	n.push()
+	if lessOrEqual(n.interval.Left, iv.Left) {
+		if n.right != nil && !n.right.descendContainingInterval(iv, visit) {
+			return false
+		}
+		if lessOrEqual(iv.Right, n.interval.Right) && !visit(n) {
+			return false
+		}
+	}
+	if n.left != nil {
+		return n.left.descendContainingInterval(iv, visit)
+	}
+	return true
+}
+
+// descendContainedInInterval calls visit for all nodes in the subtree defined
+// by this node whose interval is contained in the given interval, in
+// descending order. If visit returns false, the traversal stops and false is
+// returned.
+func (n *Node) descendContainedInInterval(
+	iv Interval, visit func(*Node) bool,
+) bool {
+	if n.endsBefore(iv.Left) {
+		return true
+	}
+	n.push()
+	if n.interval.Left.Less(iv.Right) {
+		if n.right != nil && !n.right.descendContainedInInterval(iv, visit) {
+			return false
+		}
+		if lessOrEqual(iv.Left, n.interval.Left) &&
+			n.interval.endsBefore(iv.Right) && !visit(n) {
+			return false
+		}
+	}
+	if n.left != nil && lessOrEqual(iv.Left, n.interval.Left) {
+		return n.left.descendContainedInInterval(iv, visit)
+	}
+	return true
+}
+
+// descendOverlappingInterval calls visit for all nodes in the subtree defined
+// by this node whose interval has a non-empty intersection with the given
+// interval, in descending order. If visit returns false, the traversal stops
+// and false is returned.
+func (n *Node) descendOverlappingInterval(
+	iv Interval, visit func(*Node) bool,
+) bool {
+	if n.endsBefore(iv.Left) {
+		return true
+	}
+	n.push()
+	if n.interval.Left.Less(iv.Right) {
+		if n.right != nil && !n.right.descendOverlappingInterval(iv, visit) {
+			return false
+		}
+		if !n.interval.endsBefore(iv.Left) && !visit(n) {
+			return false
+		}
+	}
+	if n.left != nil {
+		return n.left.descendOverlappingInterval(iv, visit)
+	}
+	return true
+}
+
+// nextEnd searches the subtree defined by this node for the lowest right
+// endpoint e with p <= e. The given best is the lowest such endpoint found so
+// far (nil if none), and list contains the nodes ending at best. The updated
+// best endpoint and list are returned. If done is true, no node following the
+// subtree in sort-order can end at or before best, so the search can stop.
+func (n *Node) nextEnd(p, best Point, list []*Node) (
+	newBest Point, newList []*Node, done bool,
+) {
+	if n.maxRight.Less(p) {
+		return best, list, false
+	}
+	n.push()
+	if n.left != nil {
+		best, list, done = n.left.nextEnd(p, best, list)
+		if done {
+			return best, list, true
+		}
+	}
+	if best != nil && (best.Less(n.interval.Left) ||
+		equal(best, n.interval.Left) && n.interval.Left.Less(n.interval.Right)) {
+		// A marker at best sorts before all other nodes starting at best.
+		return best, list, true
+	}
+	if lessOrEqual(p, n.interval.Right) {
+		switch {
+		case best == nil || n.interval.Right.Less(best):
+			best = n.interval.Right
+			list = append(list[:0], n)
+		case equal(n.interval.Right, best):
 			list = append(list, n)
 		}
 	}
 	if n.right != nil {
-		list = n.right.nodesOverlappingInterval(list, iv)
+		return n.right.nextEnd(p, best, list)
+	}
+	return best, list, false
+}
+
+// nodeAt returns the node at the given zero-based position i in the
+// sort-order of the subtree defined by this node. The position must satisfy
+// 0 <= i < n.size.
+func (n *Node) nodeAt(i int) *Node {
+	for {
+		n.push()
+		leftSize := 0
+		if n.left != nil {
+			leftSize = n.left.size
+		}
+		switch {
+		case i < leftSize:
+			n = n.left
+		case i == leftSize:
+			return n
+		default:
+			i -= leftSize + 1
+			n = n.right
+		}
+	}
+}
+
+// aggregateRange returns the aggregate of all nodes in the subtree defined by
+// this node whose left endpoint lies in [lo,hi). If lo or hi is nil, the range
+// is unbounded on that side.
+func (n *Node) aggregateRange(m Monoid, lo, hi Point) interface{} {
+	if n == nil {
+		return m.Identity()
+	}
+	if lo == nil && hi == nil {
+		return n.agg
+	}
+	n.push()
+	switch {
+	case lo != nil && n.interval.Left.Less(lo):
+		return n.right.aggregateRange(m, lo, hi)
+	case hi != nil && lessOrEqual(hi, n.interval.Left):
+		return n.left.aggregateRange(m, lo, hi)
+	}
+	return m.Combine(m.Combine(
+		n.left.aggregateRange(m, lo, nil), m.FromNode(n)),
+		n.right.aggregateRange(m, nil, hi),
+	)
+}
+
+// aggregateOverlapping returns the aggregate of all nodes in the subtree
+// defined by this node whose interval overlaps with the given interval.
+// If before is true, all nodes in the subtree are known to start before
+// iv.Right.
+func (n *Node) aggregateOverlapping(
+	m Monoid, iv Interval, before bool,
+) interface{} {
+	if n == nil || n.endsBefore(iv.Left) {
+		return m.Identity()
+	}
+	if before && iv.Left.Less(n.minRight) {
+		return n.agg
+	}
+	n.push()
+	if lessOrEqual(iv.Right, n.interval.Left) {
+		return n.left.aggregateOverlapping(m, iv, false)
+	}
+	result := n.left.aggregateOverlapping(m, iv, true)
+	if !n.interval.endsBefore(iv.Left) {
+		result = m.Combine(result, m.FromNode(n))
+	}
+	return m.Combine(result, n.right.aggregateOverlapping(m, iv, before))
+}
+
+// updateRange applies the given update to all nodes in the subtree defined by
+// this node whose left endpoint lies in [lo,hi). If lo or hi is nil, the range
+// is unbounded on that side. A nil node is permitted.
+func (n *Node) updateRange(
+	t *T, m LazyMonoid, lo, hi Point, delta interface{},
+) {
+	if n == nil {
+		return
+	}
+	if lo == nil && hi == nil {
+		n.apply(m, delta)
+		return
+	}
+	n.push()
+	switch {
+	case lo != nil && n.interval.Left.Less(lo):
+		n.right.updateRange(t, m, lo, hi, delta)
+	case hi != nil && lessOrEqual(hi, n.interval.Left):
+		n.left.updateRange(t, m, lo, hi, delta)
+	default:
+		n.left.updateRange(t, m, lo, nil, delta)
+		n.Value = m.ApplyValue(n.Value, delta)
+		n.right.updateRange(t, m, nil, hi, delta)
+	}
+	t.update(n)
+}
+
+// updateOverlapping applies the given update to all nodes in the subtree
+// defined by this node whose interval overlaps with the given interval.
+// If before is true, all nodes in the subtree are known to start before
+// iv.Right. A nil node is permitted.
+func (n *Node) updateOverlapping(
+	t *T, m LazyMonoid, iv Interval, before bool, delta interface{},
+) {
+	if n == nil || n.endsBefore(iv.Left) {
+		return
+	}
+	if before && iv.Left.Less(n.minRight) {
+		n.apply(m, delta)
+		return
+	}
+	n.push()
+	if lessOrEqual(iv.Right, n.interval.Left) {
+		n.left.updateOverlapping(t, m, iv, false, delta)
+	} else {
+		n.left.updateOverlapping(t, m, iv, true, delta)
+		if !n.interval.endsBefore(iv.Left) {
+			n.Value = m.ApplyValue(n.Value, delta)
+		}
+		n.right.updateOverlapping(t, m, iv, before, delta)
+	}
+	t.update(n)
+}
+
+// nodesStraddling appends all nodes in the subtree defined by this node which
+// start before the given point and end after it to the given list and returns
+// it. If startAt is true, nodes starting at p are included as well; if endAt
+// is true, nodes ending at p are included as well.
+func (n *Node) nodesStraddling(
+	list []*Node, p Point, startAt, endAt bool,
+) []*Node {
+	if n.maxRight.Less(p) || (!endAt && equal(n.maxRight, p)) {
+		return list
+	}
+	n.push()
+	if n.left != nil {
+		list = n.left.nodesStraddling(list, p, startAt, endAt)
+	}
+	if n.interval.Left.Less(p) || (startAt && equal(n.interval.Left, p)) {
+		if p.Less(n.interval.Right) || (endAt && equal(n.interval.Right, p)) {
+			list = append(list, n)
+		}
+		if n.right != nil {
+			list = n.right.nodesStraddling(list, p, startAt, endAt)
+		}
 	}
 	return list
 }
 
+// shiftFrom shifts all endpoints of the nodes in the subtree defined by this
+// node which start after the given point by d. If startAt is true, nodes
+// starting at the given point are shifted as well. A nil node is permitted.
+// The endpoints must be of type Int, and the shift must not change the order
+// of the nodes.
+func (n *Node) shiftFrom(t *T, from Int, startAt bool, d Int) {
+	if n == nil {
+		return
+	}
+	n.push()
+	left := n.interval.Left.(Int)
+	if left < from || (!startAt && left == from) {
+		n.right.shiftFrom(t, from, startAt, d)
+	} else {
+		n.left.shiftFrom(t, from, startAt, d)
+		n.interval.Left = left + d
+		n.interval.Right = n.interval.Right.(Int) + d
+		if n.right != nil {
+			n.right.applyShift(d)
+		}
+	}
+	t.update(n)
+}
+
 // Interval returns a shallow copy of the interval of this node. The caller must
 // not make deep changes to the returned interval which affect the result of
 // Less (e. g., if the dynamic type of the interval points is a pointer type).
+// If the node has been obtained before the most recent T.Shift, T.Flush must
+// be called first.
 func (n *Node) Interval() Interval {
 	return n.interval
 }
 
 // Next returns the next node in the tree sort-order (see Interval.Less).
 // If no such node exists, nil is returned.
+// If this node has been obtained before the most recent T.UpdateRange,
+// T.UpdateOverlapping or T.Shift, T.Flush must be called first, as pending
+// updates of the ancestors of this node are not propagated on the way up.
 func (n *Node) Next() *Node {
 	candidate := n
 	if candidate.right == nil {
@@ -199,8 +626,10 @@ func (n *Node) Next() *Node {
 			candidate = candidate.parent
 		}
 	}
+	candidate.push()
 	candidate = candidate.right
 	for candidate.left != nil {
+		candidate.push()
 		candidate = candidate.left
 	}
 	return candidate
@@ -209,6 +638,8 @@ func (n *Node) Next() *Node {
 // Previous returns the previous node in the tree sort-order (see
 // Interval.Less).
 // If no such node exists, nil is returned.
+// If this node has been obtained before the most recent T.UpdateRange,
+// T.UpdateOverlapping or T.Shift, T.Flush must be called first (see Next).
 func (n *Node) Previous() *Node {
 	candidate := n
 	if candidate.left == nil {
@@ -222,8 +653,10 @@ func (n *Node) Previous() *Node {
 			candidate = candidate.parent
 		}
 	}
+	candidate.push()
 	candidate = candidate.left
 	for candidate.right != nil {
+		candidate.push()
 		candidate = candidate.right
 	}
 	return candidate
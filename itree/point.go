@@ -3,6 +3,7 @@ package itree
 import (
 	"bytes"
 	"math"
+	"strings"
 )
 
 // Point represents a point in an interval.
@@ -16,9 +17,39 @@ type Point interface {
 	Less(than Point) bool
 }
 
+// Comparer is an optional interface for points which can compare themselves
+// with another point in a single step. If a point implements Comparer, the
+// tree uses Compare instead of calling Less twice to tell whether two points
+// are equal, which saves considerable work for expensive comparisons. All
+// point types of this package implement Comparer.
+type Comparer interface {
+	Point
+
+	// Compare returns a negative number, zero, or a positive number if this
+	// point is less than, equal to, or greater than the given point,
+	// respectively. Compare must be consistent with Less.
+	Compare(with Point) int
+}
+
+// compare compares the given points, using Compare if x implements Comparer,
+// and Less otherwise. It returns a negative number, zero, or a positive
+// number if x is less than, equal to, or greater than y, respectively.
+func compare(x, y Point) int {
+	if c, ok := x.(Comparer); ok {
+		return c.Compare(y)
+	}
+	switch {
+	case x.Less(y):
+		return -1
+	case y.Less(x):
+		return 1
+	}
+	return 0
+}
+
 // equal tests whether the given points are equal.
 func equal(x, y Point) bool {
-	return !(x.Less(y) || y.Less(x))
+	return compare(x, y) == 0
 }
 
 // lessOrEqual tests whether the given point x is less than or equal to the
@@ -35,6 +66,17 @@ func (x Int) Less(y Point) bool {
 	return x < y.(Int)
 }
 
+// Compare implements Comparer. It panics if y is not Int.
+func (x Int) Compare(y Point) int {
+	switch yi := y.(Int); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Int8 implements Point for the built-in int8 type.
 type Int8 int8
 
@@ -43,6 +85,17 @@ func (x Int8) Less(y Point) bool {
 	return x < y.(Int8)
 }
 
+// Compare implements Comparer. It panics if y is not Int8.
+func (x Int8) Compare(y Point) int {
+	switch yi := y.(Int8); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Int16 implements Point for the built-in int16 type.
 type Int16 int16
 
@@ -51,6 +104,17 @@ func (x Int16) Less(y Point) bool {
 	return x < y.(Int16)
 }
 
+// Compare implements Comparer. It panics if y is not Int16.
+func (x Int16) Compare(y Point) int {
+	switch yi := y.(Int16); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Int32 implements Point for the built-in int32 type.
 type Int32 int32
 
@@ -59,6 +123,17 @@ func (x Int32) Less(y Point) bool {
 	return x < y.(Int32)
 }
 
+// Compare implements Comparer. It panics if y is not Int32.
+func (x Int32) Compare(y Point) int {
+	switch yi := y.(Int32); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Int64 implements Point for the built-in int64 type.
 type Int64 int64
 
@@ -67,6 +142,17 @@ func (x Int64) Less(y Point) bool {
 	return x < y.(Int64)
 }
 
+// Compare implements Comparer. It panics if y is not Int64.
+func (x Int64) Compare(y Point) int {
+	switch yi := y.(Int64); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Uint implements Point for the built-in uint type.
 type Uint uint
 
@@ -75,6 +161,17 @@ func (x Uint) Less(y Point) bool {
 	return x < y.(Uint)
 }
 
+// Compare implements Comparer. It panics if y is not Uint.
+func (x Uint) Compare(y Point) int {
+	switch yi := y.(Uint); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Uint8 implements Point for the built-in uint8 type.
 type Uint8 uint8
 
@@ -83,6 +180,17 @@ func (x Uint8) Less(y Point) bool {
 	return x < y.(Uint8)
 }
 
+// Compare implements Comparer. It panics if y is not Uint8.
+func (x Uint8) Compare(y Point) int {
+	switch yi := y.(Uint8); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Uint16 implements Point for the built-in uint16 type.
 type Uint16 uint16
 
@@ -91,6 +199,17 @@ func (x Uint16) Less(y Point) bool {
 	return x < y.(Uint16)
 }
 
+// Compare implements Comparer. It panics if y is not Uint16.
+func (x Uint16) Compare(y Point) int {
+	switch yi := y.(Uint16); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Uint32 implements Point for the built-in uint32 type.
 type Uint32 uint32
 
@@ -99,6 +218,17 @@ func (x Uint32) Less(y Point) bool {
 	return x < y.(Uint32)
 }
 
+// Compare implements Comparer. It panics if y is not Uint32.
+func (x Uint32) Compare(y Point) int {
+	switch yi := y.(Uint32); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Uint64 implements Point for the built-in uint64 type.
 type Uint64 uint64
 
@@ -107,6 +237,17 @@ func (x Uint64) Less(y Point) bool {
 	return x < y.(Uint64)
 }
 
+// Compare implements Comparer. It panics if y is not Uint64.
+func (x Uint64) Compare(y Point) int {
+	switch yi := y.(Uint64); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Uintptr implements Point for the built-in uintptr type.
 type Uintptr uintptr
 
@@ -115,6 +256,17 @@ func (x Uintptr) Less(y Point) bool {
 	return x < y.(Uintptr)
 }
 
+// Compare implements Comparer. It panics if y is not Uintptr.
+func (x Uintptr) Compare(y Point) int {
+	switch yi := y.(Uintptr); {
+	case x < yi:
+		return -1
+	case x > yi:
+		return 1
+	}
+	return 0
+}
+
 // Float32 implements Point for the built-in float32 type.
 // NaN-values are not permitted. +0 and -0 are considered to be equal.
 type Float32 float32
@@ -129,6 +281,21 @@ func (x Float32) Less(y Point) bool {
 	return x < yf
 }
 
+// Compare implements Comparer. It panics if y is not Float32 or either of x
+// and y is NaN.
+func (x Float32) Compare(y Point) int {
+	yf := y.(Float32)
+	switch {
+	case x < yf:
+		return -1
+	case x > yf:
+		return 1
+	case x == yf:
+		return 0
+	}
+	panic("comparing NaN")
+}
+
 // Float64 implements Point for the built-in float64 type.
 // NaN-values are not permitted. +0 and -0 are considered to be equal.
 type Float64 float64
@@ -143,6 +310,21 @@ func (x Float64) Less(y Point) bool {
 	return x < yf
 }
 
+// Compare implements Comparer. It panics if y is not Float64 or either of x
+// and y is NaN.
+func (x Float64) Compare(y Point) int {
+	yf := y.(Float64)
+	switch {
+	case x < yf:
+		return -1
+	case x > yf:
+		return 1
+	case x == yf:
+		return 0
+	}
+	panic("comparing NaN")
+}
+
 // String implements Point for the built-in string type.
 type String string
 
@@ -151,19 +333,34 @@ func (x String) Less(y Point) bool {
 	return x < y.(String)
 }
 
+// Compare implements Comparer using the strings.Compare function.
+// It panics if y is not String.
+func (x String) Compare(y Point) int {
+	return strings.Compare(string(x), string(y.(String)))
+}
+
 // Bytes implements Point for the common []byte type.
 // A nil value and an empty byte slice are considered to be equal.
 type Bytes []byte
 
 // Less checks whether x < y using the bytes.Compare function.
-// It panics if y is not Bytes.
+// It panics if y is neither Bytes nor BytesEnd.
 func (x Bytes) Less(y Point) bool {
-	return bytes.Compare(x, y.(Bytes)) < 0
+	return x.Compare(y) < 0
+}
+
+// Compare implements Comparer using the bytes.Compare function.
+// It panics if y is neither Bytes nor BytesEnd.
+func (x Bytes) Compare(y Point) int {
+	if _, ok := y.(BytesEnd); ok {
+		return -1
+	}
+	return bytes.Compare(x, y.(Bytes))
 }
 
 // Point interface checks.
 var (
-	_ = []Point{
+	_ = []Comparer{
 		Int(0), Int8(0), Int16(0), Int32(0), Int64(0),
 		Uint(0), Uint8(0), Uint16(0), Uint32(0), Uint64(0), Uintptr(0),
 		Float32(0), Float64(0),
@@ -0,0 +1,74 @@
+package itree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// lessOnly is a point type which does not implement Comparer.
+type lessOnly int
+
+// Less implements Point.
+func (x lessOnly) Less(y Point) bool {
+	return x < y.(lessOnly)
+}
+
+// TestCompare tests that Compare is consistent with Less for all point types.
+func TestCompare(t *testing.T) {
+	for _, pair := range [][2]Comparer{
+		{Int(-1), Int(2)}, {Int8(-1), Int8(2)}, {Int16(-1), Int16(2)},
+		{Int32(-1), Int32(2)}, {Int64(-1), Int64(2)}, {Uint(1), Uint(2)},
+		{Uint8(1), Uint8(2)}, {Uint16(1), Uint16(2)}, {Uint32(1), Uint32(2)},
+		{Uint64(1), Uint64(2)}, {Uintptr(1), Uintptr(2)},
+		{Float32(-1), Float32(2)}, {Float64(-1), Float64(2)},
+		{String("a"), String("ab")}, {Bytes(nil), Bytes{0}},
+	} {
+		x, y := pair[0], pair[1]
+		if x.Compare(y) >= 0 || y.Compare(x) <= 0 || x.Compare(x) != 0 {
+			t.Errorf("%T: Compare inconsistent with Less", x)
+		}
+	}
+	if Float64(0).Compare(Float64(-0.0)) != 0 {
+		t.Error("+0 and -0 compare unequal")
+	}
+	if Bytes(nil).Compare(Bytes{}) != 0 {
+		t.Error("nil and empty Bytes compare unequal")
+	}
+	expectPanic(t, "comparing NaN", func() {
+		Float64(0).Compare(Float64(math.NaN()))
+	})
+	if compare(lessOnly(1), lessOnly(2)) >= 0 ||
+		compare(lessOnly(2), lessOnly(1)) <= 0 ||
+		compare(lessOnly(1), lessOnly(1)) != 0 {
+		t.Error("compare inconsistent for Less-only points")
+	}
+}
+
+// TestLessOnlyPoints tests a tree with points not implementing Comparer.
+func TestLessOnlyPoints(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	checkMap := make(map[Interval]interface{})
+	for i := 0; i != 200; i++ {
+		left := lessOnly(rand.Intn(100))
+		iv := Interval{left, left + 1 + lessOnly(rand.Intn(10))}
+		tree.ReplaceOrInsert(iv, i)
+		checkMap[iv] = i
+	}
+	for iv := range checkMap {
+		if rand.Intn(2) == 0 {
+			tree.Delete(iv)
+			delete(checkMap, iv)
+		}
+	}
+	testContents(t, &tree, checkMap)
+	var point []*Node
+	for n := tree.GetMin(); n != nil; n = n.Next() {
+		if n.interval.ContainsPoint(lessOnly(50)) {
+			point = append(point, n)
+		}
+	}
+	checkNodes(t, "NodesContainingPoint", tree.NodesContainingPoint(lessOnly(50)),
+		point)
+}
@@ -0,0 +1,117 @@
+package itree
+
+import (
+	"math/rand"
+)
+
+// SetWeight sets the weight function used by SampleWeighted. The weight of a
+// node is the result of weight applied to the node interval. Weights must be
+// non-negative. If weight is nil, the weight function is removed.
+// SetWeight recomputes the subtree weights of all nodes, so it runs in O(n)
+// time, where n is the size of this tree. Afterwards, the subtree weights are
+// maintained by all tree operations.
+func (t *T) SetWeight(weight func(Interval) float64) {
+	t.weight = weight
+	t.updateSubtree(t.root)
+}
+
+// Sample returns a node drawn uniformly at random from this tree, using the
+// given random number generator. If the tree is empty, nil is returned.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) Sample(rng *rand.Rand) *Node {
+	if t.root == nil {
+		return nil
+	}
+	return t.root.nodeAt(rng.Intn(t.length))
+}
+
+// SampleOverlapping returns a node drawn uniformly at random from the nodes
+// overlapping with the given interval, using the given random number
+// generator. If no node overlaps with iv, nil is returned.
+// SampleOverlapping collects all overlapping nodes before drawing one of them,
+// so this operation runs in O(k+log(n)) time, where k is the number of nodes
+// overlapping with iv and n is the size of this tree.
+func (t *T) SampleOverlapping(iv Interval, rng *rand.Rand) *Node {
+	nodes := t.NodesOverlappingInterval(iv)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[rng.Intn(len(nodes))]
+}
+
+// SampleWeighted returns a node drawn at random from this tree, with
+// probability proportional to its weight, using the given random number
+// generator. Nodes with zero weight are never returned. If the total weight of
+// the tree is zero, nil is returned. Weights must be non-negative.
+//
+// Only the weight function set with SetWeight benefits from the subtree
+// weights maintained by the tree: SampleWeighted(rng, nil) uses it and runs in
+// O(log(n)) time, where n is the size of this tree. It panics if no weight
+// function has been set. A non-nil weight is instead applied to every node on
+// each call, so SampleWeighted then runs in O(n) time. To sample repeatedly
+// with the same weight function, e. g., LengthWeight, set it with SetWeight
+// and pass nil.
+func (t *T) SampleWeighted(
+	rng *rand.Rand, weight func(Interval) float64,
+) *Node {
+	if weight != nil {
+		return t.sampleWeightedScan(rng, weight)
+	}
+	if t.weight == nil {
+		panic("no weight function")
+	}
+	if t.root == nil || t.root.weightSum <= 0 {
+		return nil
+	}
+	x := rng.Float64() * t.root.weightSum
+	n := t.root
+	for {
+		// Invariant: the subtree defined by n has positive weight. Rounding errors
+		// may leave x outside of the subtree weight, in which case the descent is
+		// clamped to the nearest node with positive weight.
+		n.push()
+		var lw, rw float64
+		if n.left != nil {
+			lw = n.left.weightSum
+		}
+		if n.right != nil {
+			rw = n.right.weightSum
+		}
+		if lw > 0 && x < lw {
+			n = n.left
+			continue
+		}
+		x -= lw
+		w := t.weight(n.interval)
+		switch {
+		case w > 0 && (x < w || rw <= 0):
+			return n
+		case rw > 0:
+			x -= w
+			n = n.right
+		default:
+			x = lw
+			n = n.left
+		}
+	}
+}
+
+// sampleWeightedScan implements SampleWeighted for a weight function not
+// reflected in the subtree weights. It visits all nodes once and replaces the
+// chosen node by each node with probability proportional to its share of the
+// total weight so far, so it needs no memory proportional to the tree size.
+func (t *T) sampleWeightedScan(
+	rng *rand.Rand, weight func(Interval) float64,
+) *Node {
+	var chosen *Node
+	total := 0.0
+	visitSubtree(t.root, func(n *Node) {
+		if w := weight(n.interval); w > 0 {
+			total += w
+			if rng.Float64()*total < w {
+				chosen = n
+			}
+		}
+	})
+	return chosen
+}
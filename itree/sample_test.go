@@ -0,0 +1,131 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestSample tests uniform sampling from a tree.
+func TestSample(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var tree T
+	if tree.Sample(rng) != nil {
+		t.Error("sample from empty tree")
+	}
+	for i := 0; i != 10; i++ {
+		tree.ReplaceOrInsert(Interval{Int(i), Int(i + 2)}, i)
+	}
+	counts := make(map[interface{}]int)
+	for i := 0; i != 10000; i++ {
+		counts[tree.Sample(rng).Value]++
+	}
+	for i := 0; i != 10; i++ {
+		if counts[i] < 800 || counts[i] > 1200 {
+			t.Errorf("value %d sampled %d times out of 10000", i, counts[i])
+		}
+	}
+	counts = make(map[interface{}]int)
+	iv := Interval{Int(3), Int(5)}
+	for i := 0; i != 3000; i++ {
+		n := tree.SampleOverlapping(iv, rng)
+		if !n.interval.Overlaps(iv) {
+			t.Fatalf("sampled non-overlapping node %v", n.interval)
+		}
+		counts[n.Value]++
+	}
+	for _, i := range []int{2, 3, 4} {
+		if counts[i] < 800 || counts[i] > 1200 {
+			t.Errorf("value %d sampled %d times out of 3000", i, counts[i])
+		}
+	}
+	if tree.SampleOverlapping(Interval{Int(20), Int(21)}, rng) != nil {
+		t.Error("sampled node not overlapping with query")
+	}
+}
+
+// TestSampleWeighted tests weighted sampling with random inserts and deletes.
+func TestSampleWeighted(t *testing.T) {
+	seedOnce.Do(seedRand)
+	rng := rand.New(rand.NewSource(rand.Int63()))
+	var tree T
+	expectPanic(t, "SampleWeighted without weight function", func() {
+		tree.SampleWeighted(rng, nil)
+	})
+	tree.ReplaceOrInsert(Interval{Int(0), Int(1)}, 0)
+	tree.SetWeight(func(iv Interval) float64 {
+		return float64(iv.Right.(Int) - iv.Left.(Int))
+	})
+	testInvariants(t, &tree)
+	for i := 1; i != 1000; i++ {
+		tree.ReplaceOrInsert(Interval{Int(i), Int(i + 1 + rand.Intn(10))}, i)
+		if rand.Intn(3) == 0 {
+			tree.DeleteNode(tree.Sample(rng))
+		}
+	}
+	testInvariants(t, &tree)
+	for i := 0; i != 1000; i++ {
+		if tree.SampleWeighted(rng, nil) == nil {
+			t.Fatal("nil weighted sample from non-empty tree")
+		}
+	}
+	tree = T{}
+	tree.SetWeight(func(iv Interval) float64 {
+		return float64(iv.Right.(Int) - iv.Left.(Int))
+	})
+	if tree.SampleWeighted(rng, nil) != nil {
+		t.Error("weighted sample from empty tree")
+	}
+	tree.ReplaceOrInsert(Interval{Int(0), Int(1)}, 0)
+	tree.ReplaceOrInsert(Interval{Int(1), Int(4)}, 1)
+	counts := make(map[interface{}]int)
+	for i := 0; i != 4000; i++ {
+		counts[tree.SampleWeighted(rng, nil).Value]++
+	}
+	if counts[0] < 800 || counts[0] > 1200 {
+		t.Errorf("value 0 sampled %d times out of 4000", counts[0])
+	}
+	// Nodes with zero weight are never sampled, even with the maximal random
+	// value, and explicit weight functions override the configured one.
+	tree.ReplaceOrInsertMarker(Int(4), 2)
+	tree.ReplaceOrInsertMarker(Int(5), 3)
+	maxRng := rand.New(maxSource{})
+	if n := tree.SampleWeighted(maxRng, nil); n == nil || n.Value != 1 {
+		t.Errorf("unexpected sample %v", n)
+	}
+	n := tree.SampleWeighted(maxRng, LengthWeight)
+	if n == nil || n.Interval().IsMarker() {
+		t.Errorf("unexpected sample %v with explicit weight", n)
+	}
+	counts = make(map[interface{}]int)
+	for i := 0; i != 4000; i++ {
+		counts[tree.SampleWeighted(rng, func(iv Interval) float64 {
+			switch iv.Left {
+			case Int(0):
+				return 3
+			case Int(1):
+				return 1
+			}
+			return 0
+		}).Value]++
+	}
+	if counts[0] < 2800 || counts[0] > 3200 {
+		t.Errorf("value 0 sampled %d times out of 4000", counts[0])
+	}
+	if n := tree.SampleWeighted(rng, func(Interval) float64 {
+		return 0
+	}); n != nil {
+		t.Errorf("unexpected sample %v with zero total weight", n)
+	}
+}
+
+// maxSource is a random source for which rand.Rand.Float64 always returns its
+// maximum value, the largest float64 less than one.
+type maxSource struct{}
+
+// Int63 returns the value yielding the maximal Float64 value.
+func (maxSource) Int63() int64 {
+	return 1<<63 - 1<<10
+}
+
+// Seed does nothing.
+func (maxSource) Seed(int64) {}
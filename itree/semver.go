@@ -0,0 +1,435 @@
+package itree
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SemVer implements Point for semantic versions, ordered by their precedence
+// according to Semantic Versioning 2.0.0: versions are compared by major,
+// minor and patch number, a pre-release version has lower precedence than the
+// associated normal version, and pre-releases are compared identifier by
+// identifier. Build metadata is ignored, so versions differing only in build
+// metadata are equal points.
+//
+// Besides the versions, there is a maximum point greater than all versions,
+// which is used as the right endpoint of unbounded constraint intervals.
+type SemVer struct {
+	Major, Minor, Patch uint64
+
+	// Prerelease holds the dot-separated pre-release identifiers, if any,
+	// without the leading hyphen.
+	Prerelease string
+
+	// Build holds the dot-separated build metadata, if any, without the leading
+	// plus sign.
+	Build string
+
+	// max indicates the maximum point. The other fields are zero in this case.
+	max bool
+}
+
+// maxSemVer is the point greater than all versions.
+var maxSemVer = SemVer{max: true}
+
+// minSemVer is the smallest version, 0.0.0-0.
+var minSemVer = SemVer{Prerelease: "0"}
+
+// ParseSemVer parses the given semantic version, optionally prefixed with "v".
+func ParseSemVer(s string) (SemVer, error) {
+	v, parts, err := parsePartialSemVer(strings.TrimPrefix(s, "v"))
+	if err == nil && parts != 3 {
+		err = fmt.Errorf("incomplete version %q", s)
+	}
+	return v, err
+}
+
+// parsePartialSemVer parses a semantic version of which only a prefix of the
+// major, minor and patch numbers may be given, possibly followed by an x or *
+// wildcard for the remaining numbers, and returns the number of numbers given.
+// A pre-release or build metadata requires all numbers.
+func parsePartialSemVer(s string) (v SemVer, parts int, err error) {
+	invalid := func() (SemVer, int, error) {
+		return SemVer{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	rest := s
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		v.Build = rest[i+1:]
+		if !validIdentifiers(v.Build, false) {
+			return invalid()
+		}
+		rest = rest[:i]
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		v.Prerelease = rest[i+1:]
+		if !validIdentifiers(v.Prerelease, true) {
+			return invalid()
+		}
+		rest = rest[:i]
+	}
+	numbers := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	fields := strings.Split(rest, ".")
+	if len(fields) > len(numbers) {
+		return invalid()
+	}
+	wildcard := false
+	for i, field := range fields {
+		switch {
+		case field == "x" || field == "X" || field == "*":
+			wildcard = true
+		case wildcard || !validNumber(field):
+			return invalid()
+		default:
+			*numbers[i], err = strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return invalid()
+			}
+			parts++
+		}
+	}
+	if parts != 3 && (v.Prerelease != "" || v.Build != "") {
+		return invalid()
+	}
+	return v, parts, nil
+}
+
+// validNumber checks whether s is a number without leading zeros.
+func validNumber(s string) bool {
+	if s == "" || len(s) > 1 && s[0] == '0' {
+		return false
+	}
+	for i := 0; i != len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validIdentifiers checks whether s is a list of dot-separated non-empty
+// identifiers made of ASCII letters, digits and hyphens. If prerelease is true,
+// numeric identifiers must not have leading zeros.
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for i := 0; i != len(id); i++ {
+			c := id[i]
+			if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' ||
+				c >= 'a' && c <= 'z' || c == '-') {
+				return false
+			}
+		}
+		if prerelease && isNumeric(id) && !validNumber(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumeric checks whether the given non-empty identifier consists of digits
+// only.
+func isNumeric(id string) bool {
+	return strings.Trim(id, "0123456789") == ""
+}
+
+// compareIdentifiers compares the given pre-release identifiers. Numeric
+// identifiers are compared numerically and have lower precedence than
+// alphanumeric identifiers, which are compared in ASCII order.
+func compareIdentifiers(x, y string) int {
+	xNumeric, yNumeric := isNumeric(x), isNumeric(y)
+	switch {
+	case xNumeric && yNumeric:
+		x, y = strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+		if len(x) != len(y) {
+			return compareUint64(uint64(len(x)), uint64(len(y)))
+		}
+	case xNumeric:
+		return -1
+	case yNumeric:
+		return 1
+	}
+	return strings.Compare(x, y)
+}
+
+// compareUint64 compares the given integers.
+func compareUint64(x, y uint64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// String returns the version in its usual notation, or "∞" for the maximum
+// point.
+func (x SemVer) String() string {
+	if x.max {
+		return "∞"
+	}
+	s := fmt.Sprintf("%d.%d.%d", x.Major, x.Minor, x.Patch)
+	if x.Prerelease != "" {
+		s += "-" + x.Prerelease
+	}
+	if x.Build != "" {
+		s += "+" + x.Build
+	}
+	return s
+}
+
+// Less checks whether x has lower precedence than y. It panics if y is not
+// SemVer.
+func (x SemVer) Less(y Point) bool {
+	return x.Compare(y) < 0
+}
+
+// Compare implements Comparer. It panics if y is not SemVer.
+func (x SemVer) Compare(y Point) int {
+	yv := y.(SemVer)
+	switch {
+	case x.max || yv.max:
+		return compareBool(x.max, yv.max)
+	case x.Major != yv.Major:
+		return compareUint64(x.Major, yv.Major)
+	case x.Minor != yv.Minor:
+		return compareUint64(x.Minor, yv.Minor)
+	case x.Patch != yv.Patch:
+		return compareUint64(x.Patch, yv.Patch)
+	case x.Prerelease == "" || yv.Prerelease == "":
+		// A normal version has higher precedence than its pre-releases.
+		return compareBool(x.Prerelease == "", yv.Prerelease == "")
+	}
+	xIDs := strings.Split(x.Prerelease, ".")
+	yIDs := strings.Split(yv.Prerelease, ".")
+	for i := 0; i != len(xIDs) && i != len(yIDs); i++ {
+		if c := compareIdentifiers(xIDs[i], yIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint64(uint64(len(xIDs)), uint64(len(yIDs)))
+}
+
+// compareBool compares the given booleans, with false < true.
+func compareBool(x, y bool) int {
+	switch {
+	case x == y:
+		return 0
+	case x:
+		return 1
+	}
+	return -1
+}
+
+// next returns the version immediately following x in precedence order.
+func (x SemVer) next() SemVer {
+	if x.Prerelease != "" {
+		return SemVer{
+			Major:      x.Major,
+			Minor:      x.Minor,
+			Patch:      x.Patch,
+			Prerelease: x.Prerelease + ".0",
+		}
+	}
+	return x.bump(2)
+}
+
+// bump returns the first pre-release of the version following x, where the
+// number at the given index (0 for major, 1 for minor, 2 for patch) is
+// incremented and the following numbers are reset. If the number overflows,
+// the previous number is bumped instead, or the maximum point is returned.
+func (x SemVer) bump(index int) SemVer {
+	numbers := []uint64{x.Major, x.Minor, x.Patch}
+	for ; index >= 0 && numbers[index] == math.MaxUint64; index-- {
+	}
+	if index < 0 || x.max {
+		return maxSemVer
+	}
+	numbers[index]++
+	for i := index + 1; i != len(numbers); i++ {
+		numbers[i] = 0
+	}
+	return SemVer{
+		Major:      numbers[0],
+		Minor:      numbers[1],
+		Patch:      numbers[2],
+		Prerelease: "0",
+	}
+}
+
+// ParseConstraint parses the given version constraint and returns the
+// intervals of SemVer points satisfying it, in ascending order, with
+// overlapping and adjacent intervals merged. A constraint without matching
+// versions results in no intervals.
+//
+// A constraint consists of alternatives separated by "||", each of which is a
+// whitespace-separated list of comparators, all of which must be satisfied.
+// The comparators are similar to those of npm and Cargo:
+//
+//	1.2.3, =1.2.3   exactly 1.2.3 (build metadata is ignored)
+//	1.2, 1.2.x      >=1.2.0 <1.3.0-0
+//	*, x, ""        any version
+//	>=1.2, >1.2     >=1.2.0, >=1.3.0-0
+//	<=1.2, <1.2     <1.3.0-0, <1.2.0
+//	~1.4.2, ~1.4    >=1.4.2 <1.5.0-0, >=1.4.0 <1.5.0-0
+//	~1              >=1.0.0 <2.0.0-0
+//	^1.2.3, ^0.3.1  >=1.2.3 <2.0.0-0, >=0.3.1 <0.4.0-0
+//	^0.0.3, ^0.0    >=0.0.3 <0.0.4-0, >=0.0.0 <0.1.0-0
+//
+// Apart from the upper bounds of partial versions, tilde and caret ranges,
+// which exclude the pre-releases of the bound, comparators follow precedence
+// order, e. g., <2.0.0 includes 2.0.0-alpha.
+func ParseConstraint(s string) ([]Interval, error) {
+	var result []Interval
+	for _, alternative := range strings.Split(s, "||") {
+		iv, err := parseComparators(alternative)
+		if err != nil {
+			return nil, err
+		}
+		if !iv.empty() {
+			result = append(result, iv)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Less(result[j])
+	})
+	merged := result[:0]
+	for _, iv := range result {
+		last := len(merged) - 1
+		if last >= 0 && !merged[last].Right.Less(iv.Left) {
+			if merged[last].Right.Less(iv.Right) {
+				merged[last].Right = iv.Right
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged, nil
+}
+
+// parseComparators parses the given whitespace-separated list of comparators
+// and returns the interval of SemVer points satisfying all of them. The
+// interval may be empty.
+func parseComparators(s string) (Interval, error) {
+	result := Interval{minSemVer, maxSemVer}
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		comparator := fields[i]
+		// Permit whitespace between an operator and its version.
+		if strings.Trim(comparator, "<>=~^") == "" && i+1 < len(fields) {
+			i++
+			comparator += fields[i]
+		}
+		iv, err := parseComparator(comparator)
+		if err != nil {
+			return Interval{}, err
+		}
+		if result.Left.Less(iv.Left) {
+			result.Left = iv.Left
+		}
+		if iv.Right.Less(result.Right) {
+			result.Right = iv.Right
+		}
+	}
+	return result, nil
+}
+
+// parseComparator parses the given comparator and returns the interval of
+// SemVer points satisfying it.
+func parseComparator(s string) (Interval, error) {
+	op := s[:len(s)-len(strings.TrimLeft(s, "<>=~^"))]
+	switch op {
+	case "", "=", ">=", ">", "<=", "<", "~", "^":
+	default:
+		return Interval{}, fmt.Errorf("invalid comparator %q", s)
+	}
+	v, parts, err := parsePartialSemVer(strings.TrimPrefix(s[len(op):], "v"))
+	if err != nil {
+		return Interval{}, err
+	}
+	v.Build = ""
+	if parts == 0 {
+		switch op {
+		case "", "=", ">=", "<=", "~", "^":
+			return Interval{minSemVer, maxSemVer}, nil
+		}
+		// Nothing is less or greater than any version.
+		return Interval{minSemVer, minSemVer}, nil
+	}
+	// The versions matching a partial version are [v,end).
+	end := v.bump(parts - 1)
+	if parts == 3 {
+		end = v.next()
+	}
+	switch op {
+	case "", "=":
+		return Interval{v, end}, nil
+	case ">=":
+		return Interval{v, maxSemVer}, nil
+	case ">":
+		return Interval{end, maxSemVer}, nil
+	case "<=":
+		return Interval{minSemVer, end}, nil
+	case "<":
+		return Interval{minSemVer, v}, nil
+	case "~":
+		if parts == 1 {
+			return Interval{v, v.bump(0)}, nil
+		}
+		return Interval{v, v.bump(1)}, nil
+	case "^":
+		switch {
+		case v.Major != 0 || parts == 1:
+			return Interval{v, v.bump(0)}, nil
+		case v.Minor != 0 || parts == 2:
+			return Interval{v, v.bump(1)}, nil
+		}
+		return Interval{v, v.bump(2)}, nil
+	}
+	panic("unreachable")
+}
+
+// VersionIndex maps version constraints to arbitrary values, e. g., security
+// advisories or compatibility notes, and finds the values whose constraints a
+// given version satisfies. The zero value is an empty index ready to use.
+type VersionIndex struct {
+	// tree maps the intervals of the constraints to lists of values.
+	tree T
+}
+
+// Add adds the given value for the versions satisfying the given constraint
+// (see ParseConstraint). If the constraint is invalid, an error is returned
+// and the index is left unchanged.
+func (x *VersionIndex) Add(constraint string, value interface{}) error {
+	ivs, err := ParseConstraint(constraint)
+	if err != nil {
+		return err
+	}
+	for _, iv := range ivs {
+		values, _ := x.tree.Get(iv)
+		list, _ := values.([]interface{})
+		x.tree.ReplaceOrInsert(iv, append(list, value))
+	}
+	return nil
+}
+
+// Matching returns the values whose constraints the given version satisfies,
+// ordered by the intervals of their constraints (see Interval.Less), and in
+// the order they were added for the same interval.
+// This operation runs in O(k+log(n)) time, where k is the number of intervals
+// containing the version, and n is the number of intervals in the index.
+func (x *VersionIndex) Matching(v SemVer) []interface{} {
+	var result []interface{}
+	for _, n := range x.tree.NodesContainingPoint(v) {
+		result = append(result, n.Value.([]interface{})...)
+	}
+	return result
+}
+
+// Point interface check for SemVer.
+var _ Comparer = SemVer{}
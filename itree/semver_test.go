@@ -0,0 +1,156 @@
+package itree
+
+import (
+	"fmt"
+	"testing"
+)
+
+// semVer parses the given version, panicking on error.
+func semVer(s string) SemVer {
+	v, err := ParseSemVer(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// TestSemVerOrder tests the precedence of semantic versions.
+func TestSemVerOrder(t *testing.T) {
+	// The example from the Semantic Versioning specification, extended.
+	ordered := []string{
+		"0.0.0-0",
+		"0.9.9",
+		"1.0.0-0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"1.10.0",
+		"18446744073709551615.0.0",
+	}
+	for i, x := range ordered {
+		for j, y := range ordered {
+			c := semVer(x).Compare(semVer(y))
+			if expected := sign(i - j); c != expected {
+				t.Errorf("%s vs. %s: expected %d, got %d", x, y, expected, c)
+			}
+		}
+		if !semVer(x).Less(maxSemVer) || maxSemVer.Less(semVer(x)) {
+			t.Errorf("%s not less than maximum", x)
+		}
+	}
+	if !equal(semVer("v1.2.3+build.5"), semVer("1.2.3")) {
+		t.Error("build metadata not ignored")
+	}
+	if s := semVer("1.2.3-rc.1+build.5").String(); s != "1.2.3-rc.1+build.5" {
+		t.Errorf("unexpected string %q", s)
+	}
+}
+
+// TestParseSemVerErrors tests rejecting invalid versions.
+func TestParseSemVerErrors(t *testing.T) {
+	for _, s := range []string{
+		"", "1", "1.2", "1.2.x", "01.2.3", "1.2.3-01", "1.2.3-", "1.2.3+",
+		"1.2.3-a..b", "1.2.3.4", "1.2.3-a_b", "-1.2.3", "18446744073709551616.0.0",
+	} {
+		if _, err := ParseSemVer(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+// TestParseConstraint tests the intervals of version constraints.
+func TestParseConstraint(t *testing.T) {
+	for _, c := range []struct {
+		constraint string
+		expected   string
+	}{
+		{">=1.2.0 <2.0.0", "[{1.2.0 2.0.0}]"},
+		{">= 1.2.0, < 2.0.0", ""},
+		{"~1.4", "[{1.4.0 1.5.0-0}]"},
+		{"~1.4.2", "[{1.4.2 1.5.0-0}]"},
+		{"~1", "[{1.0.0 2.0.0-0}]"},
+		{"^0.3.1", "[{0.3.1 0.4.0-0}]"},
+		{"^1.2.3", "[{1.2.3 2.0.0-0}]"},
+		{"^0.0.3", "[{0.0.3 0.0.4-0}]"},
+		{"^0.0", "[{0.0.0 0.1.0-0}]"},
+		{"^0", "[{0.0.0 1.0.0-0}]"},
+		{"1.2.3", "[{1.2.3 1.2.4-0}]"},
+		{"=1.2.3-beta", "[{1.2.3-beta 1.2.3-beta.0}]"},
+		{"1.2.x", "[{1.2.0 1.3.0-0}]"},
+		{"*", "[{0.0.0-0 ∞}]"},
+		{"", "[{0.0.0-0 ∞}]"},
+		{">1.2", "[{1.3.0-0 ∞}]"},
+		{"<=1.2", "[{0.0.0-0 1.3.0-0}]"},
+		{"<1.2", "[{0.0.0-0 1.2.0}]"},
+		{">1.2.3 <=1.2.3", "[]"},
+		{"<*", "[]"},
+		{"^1.2 || ^2.0", "[{1.2.0 2.0.0-0} {2.0.0 3.0.0-0}]"},
+		{"^2.0 || <1.0.0 || ~1.1", "[{0.0.0-0 1.0.0} {1.1.0 1.2.0-0} " +
+			"{2.0.0 3.0.0-0}]"},
+		{"^1.2 || ~1.5", "[{1.2.0 2.0.0-0}]"},
+		{"<1.0.0 || >=1.0.0", "[{0.0.0-0 ∞}]"},
+		{"<1.5 || 1.2 - 2", ""},
+		{"!=1.0.0", ""},
+		{">=", ""},
+		{"18446744073709551615.x", "[{18446744073709551615.0.0 ∞}]"},
+	} {
+		ivs, err := ParseConstraint(c.constraint)
+		if c.expected == "" {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", c.constraint, ivs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", c.constraint, err)
+		} else if s := fmt.Sprint(ivs); s != c.expected {
+			t.Errorf("%q: expected %s, got %s", c.constraint, c.expected, s)
+		}
+	}
+}
+
+// TestVersionIndex tests looking up advisories by version.
+func TestVersionIndex(t *testing.T) {
+	var index VersionIndex
+	for _, c := range []struct {
+		constraint string
+		advisory   string
+	}{
+		{">=1.2.0 <2.0.0", "A"},
+		{"^1.2", "B"},
+		{"<1.0.0 || >=3.0.0-0", "C"},
+		{"~1.4", "D"},
+	} {
+		if err := index.Add(c.constraint, c.advisory); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Add(">=1.2.0 <", "E"); err == nil {
+		t.Error("expected error")
+	}
+	testInvariants(t, &index.tree)
+	for _, c := range []struct {
+		version  string
+		expected string
+	}{
+		{"0.9.0", "[C]"},
+		{"1.2.0-rc.1", "[]"},
+		{"1.2.0", "[B A]"},
+		{"1.4.7", "[B A D]"},
+		{"2.0.0-beta", "[A]"},
+		{"2.0.0", "[]"},
+		{"3.0.0-alpha", "[C]"},
+	} {
+		matching := index.Matching(semVer(c.version))
+		if s := fmt.Sprint(matching); s != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.version, c.expected, s)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package itree
+
+// blackHeight returns the number of black nodes on any path from the given
+// node to a nil descendant, including the node itself.
+func blackHeight(n *Node) int {
+	result := 0
+	for ; n != nil; n = n.left {
+		if !n.red {
+			result++
+		}
+	}
+	return result
+}
+
+// derive returns a new tree with the same configuration as this tree and the
+// given root node.
+func (t *T) derive(root *Node) *T {
+	result := &T{
+		root:       root,
+		weight:     t.weight,
+		monoid:     t.monoid,
+		stickiness: t.stickiness,
+	}
+	if root != nil {
+		result.length = root.size
+	}
+	return result
+}
+
+// join3 joins the subtrees defined by the nodes l and r with the node k in
+// between and returns the root of the resulting subtree along with its black
+// height. The intervals in l must be less than the interval of k, which in
+// turn must be less than the intervals in r. Any of l and r may be nil, and
+// their black heights must be given as hl and hr, respectively. The links of
+// l, k and r to their previous parents are discarded, and k must have no
+// pending lazy update.
+// This operation runs in O(|hl-hr|+1) time.
+func (t *T) join3(l *Node, hl int, k, r *Node, hr int) (*Node, int) {
+	scratch := t.derive(nil)
+	k.parent, k.left, k.right, k.red = nil, nil, nil, true
+	if l != nil {
+		if l.red {
+			hl++
+		}
+		l.parent, l.red = nil, false
+	}
+	if r != nil {
+		if r.red {
+			hr++
+		}
+		r.parent, r.red = nil, false
+	}
+	var parent *Node
+	height := hl
+	if hl >= hr {
+		// Descend the right spine of l to a black node with the black height of r
+		scratch.root = l
+		c, h := l, hl
+		for !(c.black() && h == hr) {
+			c.push()
+			if !c.red {
+				h--
+			}
+			parent, c = c, c.right
+		}
+		if parent != nil {
+			parent.right = k
+		}
+		k.left, k.right = c, r
+	} else {
+		// Descend the left spine of r to a black node with the black height of l
+		scratch.root = r
+		height = hr
+		c, h := r, hr
+		for !(c.black() && h == hl) {
+			c.push()
+			if !c.red {
+				h--
+			}
+			parent, c = c, c.left
+		}
+		if parent != nil {
+			parent.left = k
+		}
+		k.left, k.right = l, c
+	}
+	k.parent = parent
+	if parent == nil {
+		scratch.root = k
+	}
+	if k.left != nil {
+		k.left.parent = k
+	}
+	if k.right != nil {
+		k.right.parent = k
+	}
+	scratch.updateAncestors(k)
+	if scratch.rebalanceRed(k) {
+		height++
+	}
+	return scratch.root, height
+}
+
+// split splits the subtree defined by the given node, whose black height is
+// h, into a subtree with all intervals less than iv and a subtree with all
+// other intervals, and returns their roots and black heights. A nil node is
+// permitted.
+// This operation runs in O(h) time, as the costs of the joins on the way up
+// telescope.
+func (t *T) split(n *Node, h int, iv Interval) (
+	lower *Node, lh int, upper *Node, uh int,
+) {
+	if n == nil {
+		return nil, 0, nil, 0
+	}
+	n.push()
+	left, right := n.left, n.right
+	if !n.red {
+		h--
+	}
+	if n.interval.Less(iv) {
+		lower, lh, upper, uh = t.split(right, h, iv)
+		lower, lh = t.join3(left, h, n, lower, lh)
+		return lower, lh, upper, uh
+	}
+	lower, lh, upper, uh = t.split(left, h, iv)
+	upper, uh = t.join3(upper, uh, n, right, h)
+	return lower, lh, upper, uh
+}
+
+// SplitAt splits this tree into a tree containing all nodes with intervals
+// less than iv, and a tree containing all other nodes. The nodes are moved to
+// the new trees, which inherit the configuration of this tree (weight
+// function, monoid and stickiness), and this tree is left empty.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) SplitAt(iv Interval) (lower, upper *T) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	l, _, u, _ := t.split(t.root, blackHeight(t.root), iv)
+	lower, upper = t.derive(l), t.derive(u)
+	t.root, t.length, t.ends = nil, 0, nil
+	t.mods++
+	return lower, upper
+}
+
+// Join joins the given trees into a new tree. All intervals in lower must be
+// less than all intervals in upper. Both trees must have the same
+// configuration (weight function, monoid and stickiness). The nodes are moved
+// to the new tree, and lower and upper are left empty.
+// This operation runs in O(log(n)) time, where n is the size of the resulting
+// tree.
+func Join(lower, upper *T) *T {
+	lower.ends, upper.ends = nil, nil
+	var result *T
+	switch {
+	case lower.root == nil:
+		result = upper.derive(upper.root)
+	case upper.root == nil:
+		result = lower.derive(lower.root)
+	default:
+		k := lower.GetMax()
+		if !k.interval.Less(upper.GetMin().interval) {
+			panic("trees not ordered")
+		}
+		lower.DeleteNode(k)
+		hl, hu := blackHeight(lower.root), blackHeight(upper.root)
+		root, _ := lower.join3(lower.root, hl, k, upper.root, hu)
+		result = lower.derive(root)
+	}
+	lower.root, lower.length = nil, 0
+	upper.root, upper.length = nil, 0
+	lower.mods++
+	upper.mods++
+	return result
+}
@@ -0,0 +1,82 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testContents checks that the given tree contains exactly the given
+// interval → value mappings.
+func testContents(t *testing.T, tree *T, checkMap map[Interval]interface{}) {
+	testInvariants(t, tree)
+	if tree.Len() != len(checkMap) {
+		t.Errorf("expected %d nodes, got %d", len(checkMap), tree.Len())
+	}
+	for iv, value := range checkMap {
+		n := tree.GetMarker(iv.Left)
+		if !iv.IsMarker() {
+			n = tree.GetNode(iv)
+		}
+		if n == nil || n.Value != value {
+			t.Errorf("expected %v → %v, got %v", iv, value, n)
+		}
+	}
+}
+
+// TestSplitJoin tests splitting and joining random trees.
+func TestSplitJoin(t *testing.T) {
+	seedOnce.Do(seedRand)
+	for i := 0; i != 100; i++ {
+		tree := NewWithMonoid(listMonoid{})
+		lowerMap := make(map[Interval]interface{})
+		upperMap := make(map[Interval]interface{})
+		pivot := randomInterval()
+		for j, size := 0, rand.Intn(200); j != size; j++ {
+			iv := randomInterval()
+			tree.ReplaceOrInsert(iv, j)
+			if iv.Less(pivot) {
+				lowerMap[iv] = j
+			} else {
+				upperMap[iv] = j
+			}
+		}
+		lower, upper := tree.SplitAt(pivot)
+		if tree.Len() != 0 || tree.root != nil {
+			t.Error("tree not empty after split")
+		}
+		testContents(t, lower, lowerMap)
+		testAggregates(t, lower)
+		testContents(t, upper, upperMap)
+		testAggregates(t, upper)
+		joined := Join(lower, upper)
+		if lower.Len() != 0 || upper.Len() != 0 {
+			t.Error("trees not empty after join")
+		}
+		for iv, value := range lowerMap {
+			upperMap[iv] = value
+		}
+		testContents(t, joined, upperMap)
+		testAggregates(t, joined)
+	}
+	for i := 0; i != 50; i++ {
+		var lower, upper T
+		checkMap := make(map[Interval]interface{})
+		nl, nu := rand.Intn(1<<uint(rand.Intn(10))), rand.Intn(1<<uint(rand.Intn(10)))
+		for j := 0; j != nl+nu; j++ {
+			iv := Interval{Int(j), Int(j + 1)}
+			if j < nl {
+				lower.ReplaceOrInsert(iv, j)
+			} else {
+				upper.ReplaceOrInsert(iv, j)
+			}
+			checkMap[iv] = j
+		}
+		testContents(t, Join(&lower, &upper), checkMap)
+	}
+	var lower, upper T
+	lower.ReplaceOrInsert(Interval{Int(1), Int(2)}, 0)
+	upper.ReplaceOrInsert(Interval{Int(0), Int(3)}, 1)
+	expectPanic(t, "join unordered trees", func() {
+		Join(&lower, &upper)
+	})
+}
@@ -0,0 +1,151 @@
+package itree
+
+import (
+	"time"
+)
+
+// Time implements Point for the time.Time type, comparing times with
+// time.Time.Before. Points should be created with TimeOf or the interval
+// constructors of this package, which strip the monotonic clock reading:
+// time.Time compares times by their monotonic clock readings if both have
+// one, so times from time.Now and times parsed or computed from wall clock
+// readings could otherwise be ordered inconsistently.
+type Time time.Time
+
+// TimeOf returns the given time as Time, with its monotonic clock reading
+// stripped.
+func TimeOf(t time.Time) Time {
+	return Time(t.Round(0))
+}
+
+// Less checks whether x is before y. It panics if y is not Time.
+func (x Time) Less(y Point) bool {
+	return time.Time(x).Before(time.Time(y.(Time)))
+}
+
+// Compare implements Comparer. It panics if y is not Time.
+func (x Time) Compare(y Point) int {
+	yt := time.Time(y.(Time))
+	switch {
+	case time.Time(x).Before(yt):
+		return -1
+	case time.Time(x).After(yt):
+		return 1
+	}
+	return 0
+}
+
+// Until returns the duration y - x, which is the metric of Time points. If the
+// result exceeds the range of time.Duration, the maximum or minimum duration
+// is returned. Time does not implement Metric, as its float64 distances cannot
+// represent durations beyond 2^53 nanoseconds, about 104 days, exactly.
+func (x Time) Until(y Time) time.Duration {
+	return time.Time(y).Sub(time.Time(x))
+}
+
+// TimeInterval returns the interval [start,start+dur) of Time points.
+func TimeInterval(start time.Time, dur time.Duration) Interval {
+	return Interval{
+		Left:  TimeOf(start),
+		Right: TimeOf(start.Add(dur)),
+	}
+}
+
+// TimeRange returns the interval [start,end) of Time points.
+func TimeRange(start, end time.Time) Interval {
+	return Interval{
+		Left:  TimeOf(start),
+		Right: TimeOf(end),
+	}
+}
+
+// TimeDuration returns the duration of the given interval of Time points,
+// i. e., the distance from its left to its right endpoint (see Time.Until).
+func TimeDuration(iv Interval) time.Duration {
+	return iv.Left.(Time).Until(iv.Right.(Time))
+}
+
+// CalendarUnit is a unit of the calendar to which intervals of Time points can
+// be aligned.
+type CalendarUnit int
+
+// CalendarUnit values.
+const (
+	// CalendarHour is an hour of the local clock.
+	CalendarHour CalendarUnit = iota
+
+	// CalendarDay is a day, from midnight to midnight.
+	CalendarDay
+
+	// CalendarISOWeek is a week according to ISO 8601, from Monday to Sunday.
+	CalendarISOWeek
+)
+
+// Start returns the start of the calendar unit containing the given time in
+// the given location. Days and weeks start at midnight; if a daylight saving
+// time transition skips midnight, the start is normalised by time.Date.
+// The result has no monotonic clock reading.
+func (u CalendarUnit) Start(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc).Round(0)
+	switch u {
+	case CalendarHour:
+		return t.Add(-time.Duration(t.Minute())*time.Minute -
+			time.Duration(t.Second())*time.Second -
+			time.Duration(t.Nanosecond()))
+	case CalendarDay:
+		year, month, day := t.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case CalendarISOWeek:
+		year, month, day := t.Date()
+		sinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-sinceMonday, 0, 0, 0, 0, loc)
+	}
+	panic("invalid calendar unit")
+}
+
+// next returns the start of the calendar unit following the one starting at
+// the given time in the given location.
+func (u CalendarUnit) next(start time.Time, loc *time.Location) time.Time {
+	switch u {
+	case CalendarHour:
+		return u.Start(start.Add(time.Hour), loc)
+	case CalendarDay:
+		year, month, day := start.Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	default:
+		year, month, day := start.Date()
+		return time.Date(year, month, day+7, 0, 0, 0, 0, loc)
+	}
+}
+
+// AlignInterval returns the smallest interval of Time points containing the
+// given interval of Time points whose endpoints are starts of the given
+// calendar unit in the given location.
+func AlignInterval(iv Interval, u CalendarUnit, loc *time.Location) Interval {
+	start := u.Start(time.Time(iv.Left.(Time)), loc)
+	end := time.Time(iv.Right.(Time))
+	if endStart := u.Start(end, loc); endStart.Before(end) {
+		end = u.next(endStart, loc)
+	} else {
+		end = endStart
+	}
+	return TimeRange(start, end)
+}
+
+// SplitByCalendar splits the given interval of Time points at the starts of
+// the given calendar unit in the given location, and returns the non-empty
+// parts in ascending order, e. g., one part per day touched by the interval.
+func SplitByCalendar(
+	iv Interval, u CalendarUnit, loc *time.Location,
+) []Interval {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	var parts []Interval
+	start, end := time.Time(iv.Left.(Time)), time.Time(iv.Right.(Time))
+	for next := u.next(u.Start(start, loc), loc); next.Before(end); {
+		parts = append(parts, TimeRange(start, next))
+		start, next = next, u.next(next, loc)
+	}
+	return append(parts, TimeRange(start, end))
+}
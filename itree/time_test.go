@@ -0,0 +1,83 @@
+package itree
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTime tests Time points and intervals.
+func TestTime(t *testing.T) {
+	now := time.Now()
+	wall := now.Round(0)
+	if !equal(TimeOf(now), TimeOf(wall)) {
+		t.Error("monotonic clock reading not stripped")
+	}
+	iv := TimeInterval(now, time.Hour)
+	if d := TimeDuration(iv); d != time.Hour {
+		t.Errorf("expected duration 1h, got %v", d)
+	}
+	long := TimeInterval(now, 1<<53+1)
+	if d := TimeDuration(long); d != 1<<53+1 {
+		t.Errorf("expected duration 2^53+1ns, got %v", d)
+	}
+	if !iv.ContainsPoint(TimeOf(now.Add(time.Minute))) ||
+		iv.ContainsPoint(TimeOf(now.Add(time.Hour))) {
+		t.Error("unexpected containment")
+	}
+	if c := TimeOf(now).Compare(TimeOf(now.Add(1))); c >= 0 {
+		t.Errorf("expected negative comparison, got %d", c)
+	}
+	var tree T
+	tree.ReplaceOrInsert(TimeRange(wall, wall.Add(time.Minute)), 0)
+	if n := tree.GetNode(TimeInterval(now, time.Minute)); n == nil {
+		t.Error("interval not found by time with monotonic clock reading")
+	}
+}
+
+// TestCalendarUnit tests aligning times to calendar units.
+func TestCalendarUnit(t *testing.T) {
+	// A zone with a non-integral hour offset.
+	loc := time.FixedZone("IST", 5*3600+1800)
+	at := time.Date(2021, time.January, 1, 14, 47, 12, 5, loc) // a Friday
+	for _, c := range []struct {
+		unit  CalendarUnit
+		start time.Time
+	}{
+		{CalendarHour, time.Date(2021, time.January, 1, 14, 0, 0, 0, loc)},
+		{CalendarDay, time.Date(2021, time.January, 1, 0, 0, 0, 0, loc)},
+		{CalendarISOWeek, time.Date(2020, time.December, 28, 0, 0, 0, 0, loc)},
+	} {
+		if start := c.unit.Start(at.UTC(), loc); !start.Equal(c.start) {
+			t.Errorf("unit %d: expected start %v, got %v", c.unit, c.start, start)
+		}
+	}
+	iv := TimeRange(at, at.Add(36*time.Hour))
+	aligned := AlignInterval(iv, CalendarDay, loc)
+	expected := TimeRange(time.Date(2021, time.January, 1, 0, 0, 0, 0, loc),
+		time.Date(2021, time.January, 4, 0, 0, 0, 0, loc))
+	if !aligned.Equal(expected) {
+		t.Errorf("expected aligned interval %v, got %v", expected, aligned)
+	}
+	aligned = AlignInterval(expected, CalendarDay, loc)
+	if !aligned.Equal(expected) {
+		t.Errorf("aligned interval %v changed to %v", expected, aligned)
+	}
+	parts := SplitByCalendar(iv, CalendarDay, loc)
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %v", parts)
+	}
+	if !equal(parts[0].Left, iv.Left) || !equal(parts[2].Right, iv.Right) {
+		t.Errorf("parts %v do not cover %v", parts, iv)
+	}
+	for i := 1; i != len(parts); i++ {
+		boundary := time.Time(parts[i].Left.(Time))
+		if !equal(parts[i-1].Right, parts[i].Left) ||
+			!CalendarDay.Start(boundary, loc).Equal(boundary) {
+			t.Errorf("part %v does not start a day", parts[i])
+		}
+	}
+	hours := SplitByCalendar(TimeInterval(at, time.Minute), CalendarHour, loc)
+	if len(hours) != 1 {
+		t.Errorf("expected a single part, got %v", hours)
+	}
+}
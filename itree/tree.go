@@ -1,14 +1,52 @@
 package itree
 
+import (
+	"errors"
+)
+
+// ErrIntervalExists is returned when an interval cannot be stored in a tree
+// because the tree already contains a node with an equal interval.
+var ErrIntervalExists = errors.New("interval already exists in tree")
+
 // T represents an interval tree.
 // The zero value represents an empty tree.
-// This data structure is not safe for concurrent modification.
+// This data structure is not safe for concurrent modification. While lazy
+// updates are pending (see UpdateRange, UpdateOverlapping and Shift), it is not
+// safe for concurrent reads either, as lookups, queries and iteration
+// propagate pending updates to the nodes they visit. Setters such as SetWeight
+// and SetEndIndex modify the tree as well.
 type T struct {
 	// root is the root node of this interval tree.
 	root *Node
 
 	// length is the total number of nodes in this tree.
 	length int
+
+	// weight is the weight function set with SetWeight, or nil.
+	weight func(Interval) float64
+
+	// monoid is the monoid for user-defined subtree aggregates, or nil.
+	monoid Monoid
+
+	// stickiness defines how Shift treats endpoints at the edit position.
+	stickiness Stickiness
+
+	// ends is the index of the nodes by their right endpoints used by NextEnd
+	// (see indexEnd), or nil if it has not been built.
+	ends *T
+
+	// mods counts the modifications of the shape of this tree and of the
+	// intervals of its nodes, so that stale EntryRefs can be detected.
+	mods int
+}
+
+// Entry is an interval → value mapping.
+type Entry struct {
+	// Interval is the interval mapped to Value.
+	Interval Interval
+
+	// Value is the value.
+	Value interface{}
 }
 
 // Len returns the number of elements in this interval tree.
@@ -16,8 +54,71 @@ func (t *T) Len() int {
 	return t.length
 }
 
+// update recomputes the augmented data of the given node (maxRight, minRight,
+// markers, size, weightSum and agg) from its interval, value and children.
+func (t *T) update(n *Node) {
+	n.maxRight = n.interval.Right
+	n.minRight = n.interval.Right
+	n.markers = !n.interval.Left.Less(n.interval.Right)
+	n.size = 1
+	n.weightSum = 0
+	if t.weight != nil {
+		n.weightSum = t.weight(n.interval)
+	}
+	if t.monoid != nil {
+		n.agg = t.monoid.FromNode(n)
+	}
+	if n.left != nil {
+		if n.maxRight.Less(n.left.maxRight) {
+			n.maxRight = n.left.maxRight
+		}
+		if n.left.minRight.Less(n.minRight) {
+			n.minRight = n.left.minRight
+		}
+		n.markers = n.markers || n.left.markers
+		n.size += n.left.size
+		n.weightSum += n.left.weightSum
+		if t.monoid != nil {
+			n.agg = t.monoid.Combine(n.left.agg, n.agg)
+		}
+	}
+	if n.right != nil {
+		if n.maxRight.Less(n.right.maxRight) {
+			n.maxRight = n.right.maxRight
+		}
+		if n.right.minRight.Less(n.minRight) {
+			n.minRight = n.right.minRight
+		}
+		n.markers = n.markers || n.right.markers
+		n.size += n.right.size
+		n.weightSum += n.right.weightSum
+		if t.monoid != nil {
+			n.agg = t.monoid.Combine(n.agg, n.right.agg)
+		}
+	}
+}
+
+// updateAncestors calls update on the given node and all its ancestors.
+func (t *T) updateAncestors(n *Node) {
+	for ; n != nil; n = n.parent {
+		t.update(n)
+	}
+}
+
+// updateSubtree calls update on all nodes in the subtree defined by the given
+// node, children first.
+func (t *T) updateSubtree(n *Node) {
+	if n == nil {
+		return
+	}
+	n.push()
+	t.updateSubtree(n.left)
+	t.updateSubtree(n.right)
+	t.update(n)
+}
+
 // rotateLeft performs a left rotation of the given node m, which must have a
-// right child n, and fixes the maxRight values of the affected nodes.
+// right child n, and fixes the augmented data of the affected nodes.
 // Specifically, the following operation is performed (y may be nil):
 //
 //     m                n
@@ -28,6 +129,8 @@ func (t *T) Len() int {
 //
 // The order defined by Interval.Less is left invariant by this operation.
 func (t *T) rotateLeft(m *Node) {
+	m.push()
+	m.right.push()
 	parent := m.parent
 	n := m.right
 	y := n.left
@@ -52,21 +155,13 @@ func (t *T) rotateLeft(m *Node) {
 	n.left = m
 	m.right = y
 
-	// Update maxRight
-	m.maxRight = m.interval.Right
-	if m.left != nil && m.maxRight.Less(m.left.maxRight) {
-		m.maxRight = m.left.maxRight
-	}
-	if y != nil && m.maxRight.Less(y.maxRight) {
-		m.maxRight = y.maxRight
-	}
-	if n.maxRight.Less(m.maxRight) {
-		n.maxRight = m.maxRight
-	}
+	// Update augmented data
+	t.update(m)
+	t.update(n)
 }
 
 // rotateRight performs a right rotation of the given node n, which must have a
-// left child m, and fixes the maxRight values of the affected nodes.
+// left child m, and fixes the augmented data of the affected nodes.
 // Specifically, the following operation is performed (y may be nil):
 //
 //     m                n
@@ -77,6 +172,8 @@ func (t *T) rotateLeft(m *Node) {
 //
 // The order defined by Interval.Less is left invariant by this operation.
 func (t *T) rotateRight(n *Node) {
+	n.push()
+	n.left.push()
 	parent := n.parent
 	m := n.left
 	y := m.right
@@ -101,30 +198,71 @@ func (t *T) rotateRight(n *Node) {
 	n.left = y
 	m.right = n
 
-	// Update maxRight
-	n.maxRight = n.interval.Right
-	if y != nil && n.maxRight.Less(y.maxRight) {
-		n.maxRight = y.maxRight
-	}
-	if n.right != nil && n.maxRight.Less(n.right.maxRight) {
-		n.maxRight = n.right.maxRight
+	// Update augmented data
+	t.update(n)
+	t.update(m)
+}
+
+// locate searches this tree for the given interval. If a node with an equal
+// interval exists, it is returned as found. Otherwise, parent and link
+// describe where a node with the interval would have to be linked (see
+// linkNode).
+func (t *T) locate(iv Interval) (found, parent *Node, link **Node) {
+	link = &t.root
+	for current := t.root; current != nil; current = *link {
+		current.push()
+		switch c := iv.compare(current.interval); {
+		case c > 0:
+			link = &current.right
+		case c < 0:
+			link = &current.left
+		default:
+			return current, nil, nil
+		}
+		parent = current
 	}
-	if m.maxRight.Less(n.maxRight) {
-		m.maxRight = n.maxRight
+	return nil, parent, link
+}
+
+// linkNode links the given node, which must not be part of any tree, into
+// this tree as child of the given parent (or as root if parent is nil) at
+// the given link, as returned by locate, and rebalances the tree.
+func (t *T) linkNode(n, parent *Node, link **Node) {
+	n.parent, n.left, n.right, n.pending, n.red = parent, nil, nil, nil, true
+	*link = n
+	t.updateAncestors(n)
+	t.rebalanceRed(n)
+	t.length++
+	t.mods++
+	t.indexEnd(n)
+}
+
+// insertNode inserts the given node, which must not be part of any tree, into
+// this tree, keeping its interval and value. If the tree already contains a
+// node with an equal interval, the tree is left unchanged and false is
+// returned.
+func (t *T) insertNode(n *Node) bool {
+	found, parent, link := t.locate(n.interval)
+	if found != nil {
+		return false
 	}
+	t.linkNode(n, parent, link)
+	return true
 }
 
 // GetNode retrieves the node for the given interval from this tree.
 // If no such node exists, nil is returned.
+// GetNode panics if iv is empty, including markers (see GetMarker).
 func (t *T) GetNode(iv Interval) *Node {
 	if iv.empty() {
 		panic("empty interval")
 	}
 	for current := t.root; current != nil; {
-		switch {
-		case iv.Less(current.interval):
+		current.push()
+		switch c := iv.compare(current.interval); {
+		case c < 0:
 			current = current.left
-		case current.interval.Less(iv):
+		case c > 0:
 			current = current.right
 		default:
 			return current
@@ -138,6 +276,7 @@ func (t *T) GetNode(iv Interval) *Node {
 func (t *T) GetMin() *Node {
 	var candidate *Node
 	for current := t.root; current != nil; current = current.left {
+		current.push()
 		candidate = current
 	}
 	return candidate
@@ -148,6 +287,7 @@ func (t *T) GetMin() *Node {
 func (t *T) GetMax() *Node {
 	var candidate *Node
 	for current := t.root; current != nil; current = current.right {
+		current.push()
 		candidate = current
 	}
 	return candidate
@@ -161,6 +301,7 @@ func (t *T) GetLess(iv Interval) *Node {
 	}
 	var candidate *Node
 	for current := t.root; current != nil; {
+		current.push()
 		switch {
 		default:
 			current = current.left
@@ -180,6 +321,7 @@ func (t *T) GetLessEqual(iv Interval) *Node {
 	}
 	var candidate *Node
 	for current := t.root; current != nil; {
+		current.push()
 		switch {
 		case iv.Less(current.interval):
 			current = current.left
@@ -199,6 +341,7 @@ func (t *T) GetGreater(iv Interval) *Node {
 	}
 	var candidate *Node
 	for current := t.root; current != nil; {
+		current.push()
 		switch {
 		case iv.Less(current.interval):
 			candidate = current
@@ -219,6 +362,7 @@ func (t *T) GetGreaterEqual(iv Interval) *Node {
 	}
 	var candidate *Node
 	for current := t.root; current != nil; {
+		current.push()
 		switch {
 		default:
 			candidate = current
@@ -233,6 +377,7 @@ func (t *T) GetGreaterEqual(iv Interval) *Node {
 // Get retrieves the value for the specified interval. If the given interval
 // is not part of this tree, (nil, false) is returned. Otherwise, the value and
 // present == true is returned.
+// Get panics if iv is empty, including markers (see GetMarker).
 func (t *T) Get(iv Interval) (value interface{}, present bool) {
 	node := t.GetNode(iv)
 	if node == nil {
@@ -251,12 +396,10 @@ func (t *T) ReplaceOrInsert(interval Interval, value interface{}) (
 		panic("empty interval")
 	}
 	if t.length == 0 { // empty tree
-		t.root = &Node{
+		t.linkNode(&Node{
 			Value:    value,
 			interval: interval,
-			maxRight: interval.Right,
-		}
-		t.length = 1
+		}, nil, &t.root)
 		return nil, false
 	}
 
@@ -321,16 +464,158 @@ func (t *T) NodesOverlappingInterval(iv Interval) []*Node {
 	return t.root.nodesOverlappingInterval(result, iv)
 }
 
+// DescendContainingPoint calls visit for all nodes containing the given
+// point, in descending order of their intervals, until visit returns false.
+// Finding the first m nodes this way takes O(m+log(n)) time, where n is the
+// size of this tree.
+func (t *T) DescendContainingPoint(p Point, visit func(*Node) bool) {
+	if t.root == nil {
+		return
+	}
+	t.root.descendContainingPoint(p, visit)
+}
+
+// DescendContainingInterval calls visit for all nodes containing the given
+// interval, in descending order of their intervals, until visit returns false.
+// Finding the first m nodes this way takes O(m+log(n)) time, where n is the
+// size of this tree.
+func (t *T) DescendContainingInterval(iv Interval, visit func(*Node) bool) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	if t.root == nil {
+		return
+	}
+	t.root.descendContainingInterval(iv, visit)
+}
+
+// DescendContainedInInterval calls visit for all nodes contained in the given
+// interval, in descending order of their intervals, until visit returns false.
+// Finding the first m nodes this way takes O(m+log(n)) time, where n is the
+// size of this tree.
+func (t *T) DescendContainedInInterval(iv Interval, visit func(*Node) bool) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	if t.root == nil {
+		return
+	}
+	t.root.descendContainedInInterval(iv, visit)
+}
+
+// DescendOverlappingInterval calls visit for all nodes overlapping with the
+// given interval, in descending order of their intervals, until visit returns
+// false.
+// Finding the first m nodes this way takes O(m+log(n)) time, where n is the
+// size of this tree.
+func (t *T) DescendOverlappingInterval(iv Interval, visit func(*Node) bool) {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	if t.root == nil {
+		return
+	}
+	t.root.descendOverlappingInterval(iv, visit)
+}
+
+// Descend calls visit for all nodes with intervals less than or equal to the
+// given interval, in descending order, until visit returns false.
+func (t *T) Descend(from Interval, visit func(*Node) bool) {
+	for n := t.GetLessEqual(from); n != nil && visit(n); n = n.Previous() {
+	}
+}
+
+// NextStart returns the lowest left endpoint s in this tree with p <= s,
+// along with all nodes starting at s, ordered by their intervals.
+// If no such endpoint exists, (nil, nil) is returned.
+// This operation runs in O(m+log(n)) time, where m is the number of returned
+// nodes and n is the size of this tree.
+func (t *T) NextStart(p Point) (Point, []*Node) {
+	var candidate *Node
+	for current := t.root; current != nil; {
+		current.push()
+		if current.interval.Left.Less(p) {
+			current = current.right
+		} else {
+			candidate = current
+			current = current.left
+		}
+	}
+	if candidate == nil {
+		return nil, nil
+	}
+	start := candidate.interval.Left
+	result := make([]*Node, 0, 1)
+	for n := candidate; n != nil && equal(n.interval.Left, start); n = n.Next() {
+		result = append(result, n)
+	}
+	return start, result
+}
+
+// NextEnd returns the lowest right endpoint e in this tree with p <= e, along
+// with all nodes ending at e, ordered by their intervals.
+// If no such endpoint exists, (nil, nil) is returned.
+// This operation runs in O(k+log(n)) time, where k is the number of nodes
+// whose interval starts before e and ends at or after p, and n is the size of
+// this tree. If the end index is enabled (see SetEndIndex), it runs in
+// O(m+log(n)) time instead, where m is the number of returned nodes. NextEnd
+// never builds the index itself.
+func (t *T) NextEnd(p Point) (Point, []*Node) {
+	if t.root == nil {
+		return nil, nil
+	}
+	if t.ends == nil {
+		end, result, _ := t.root.nextEnd(p, nil, nil)
+		return end, result
+	}
+	_, first := t.ends.NextStart(Tuple{p})
+	if first == nil {
+		return nil, nil
+	}
+	end := first[0].interval.Left.(Tuple)[0]
+	var result []*Node
+	for n := first[0]; n != nil; n = n.Next() {
+		if !equal(n.interval.Left.(Tuple)[0], end) {
+			break
+		}
+		result = append(result, n.Value.(*Node))
+	}
+	return end, result
+}
+
+// NextBoundary returns the lowest endpoint b in this tree with p <= b, along
+// with all nodes starting at b and all nodes ending at b, each ordered by
+// their intervals. If no such endpoint exists, (nil, nil, nil) is returned.
+// This operation has the combined complexity of NextStart and NextEnd.
+func (t *T) NextBoundary(p Point) (b Point, starting, ending []*Node) {
+	start, starting := t.NextStart(p)
+	end, ending := t.NextEnd(p)
+	switch {
+	case start == nil:
+		return end, nil, ending
+	case end == nil || start.Less(end):
+		return start, starting, nil
+	case end.Less(start):
+		return end, nil, ending
+	default:
+		return start, starting, ending
+	}
+}
+
 // DeleteNode deletes the given node from this tree. The given node must be
 // part of this tree. After deletion the given node should no longer be used.
 func (t *T) DeleteNode(n *Node) {
+	n.flush()
+	t.unindexEnd(n)
+	t.mods++
 	if n.left != nil && n.right != nil {
 		// n has two children, so we reduce to the one child case first by swapping
 		// n with the maximum lower node in its subtree.
-		// There is no need to update maxRight at this step, since maxRight will
-		// have to be fixed in all ancestors of n later on anyway.
+		// There is no need to update the augmented data at this step, since it
+		// will have to be fixed in all ancestors of n later on anyway.
 		parent := n.parent
 		candidate := n.left
+		candidate.push()
 		if candidate.right == nil {
 			// OK, we can swap n with candidate
 			if parent != nil {
@@ -353,6 +638,7 @@ func (t *T) DeleteNode(n *Node) {
 			// Search for rightmost descendant of candidate and swap with n
 			for dowhile := true; dowhile; dowhile = candidate.right != nil {
 				candidate = candidate.right
+				candidate.push()
 			}
 			if parent != nil {
 				if parent.left == n {
@@ -396,16 +682,8 @@ func (t *T) DeleteNode(n *Node) {
 		t.root = child
 	}
 
-	// Fix maxRight in all ancestors of n
-	for current := parent; current != nil; current = current.parent {
-		current.maxRight = current.interval.Right
-		if current.left != nil && current.maxRight.Less(current.left.maxRight) {
-			current.maxRight = current.left.maxRight
-		}
-		if current.right != nil && current.maxRight.Less(current.right.maxRight) {
-			current.maxRight = current.right.maxRight
-		}
-	}
+	// Fix augmented data in all ancestors of n
+	t.updateAncestors(parent)
 
 	// Finally, rebalance the tree if necessary
 	if !n.red {
@@ -441,6 +719,8 @@ func (t *T) DeleteAndDescend(n *Node) *Node {
 // Delete deletes the node for the specified interval from the tree.
 // If no such node exists, (nil, false) is returned. Otherwise, the value of
 // the deleted node is returned with deleted == true.
+// Delete panics if the interval is empty, including markers (see
+// DeleteMarker).
 func (t *T) Delete(interval Interval) (value interface{}, deleted bool) {
 	n := t.GetNode(interval)
 	if n == nil {
@@ -451,23 +731,109 @@ func (t *T) Delete(interval Interval) (value interface{}, deleted bool) {
 	return value, true
 }
 
+// SetInterval changes the interval of the given node, which must be part of
+// this tree, to iv. The node stays part of the tree under its new interval,
+// so pointers to it remain valid. The node is repositioned in the tree only
+// if its position in the sort-order changes. If the tree already contains
+// another node with an interval equal to iv, the tree is left unchanged and
+// ErrIntervalExists is returned. SetInterval panics if iv is empty, including
+// markers, so a node cannot be turned into a marker; the node itself may be a
+// marker, though.
+// This operation runs in O(log(n)) time, where n is the size of this tree.
+func (t *T) SetInterval(n *Node, iv Interval) error {
+	if iv.empty() {
+		panic("empty interval")
+	}
+	n.flush()
+	if !iv.Equal(n.interval) && t.GetNode(iv) != nil {
+		return ErrIntervalExists
+	}
+	previous, next := n.Previous(), n.Next()
+	if (previous == nil || previous.interval.Less(iv)) &&
+		(next == nil || iv.Less(next.interval)) {
+		t.unindexEnd(n)
+		n.interval = iv
+		t.updateAncestors(n)
+		t.indexEnd(n)
+		t.mods++
+		return nil
+	}
+	t.DeleteNode(n)
+	n.interval = iv
+	t.insertNode(n)
+	return nil
+}
+
+// Compact walks this tree in sort-order (see Interval.Less) and merges each
+// node with the following node if their values are equal as reported by the
+// given function, and their intervals are adjacent (see Interval.Adjacent),
+// e. g., the right endpoint of the first interval equals the left endpoint of
+// the second. If mergeOverlapping is true, nodes with overlapping intervals
+// are merged as well. A merged node keeps the value of the first node, and its
+// interval becomes the union of both intervals. If the points implement
+// Discrete, markers are merged like the unit intervals they cover. Other
+// markers are never adjacent to an interval, as the union with an interval
+// ending at the marked point cannot be represented, so they are only merged
+// if mergeOverlapping is true, into intervals containing or starting at the
+// marked point. The number of removed nodes is returned.
+// This operation runs in O(n·log(n)) time, where n is the size of this tree.
+func (t *T) Compact(
+	equalValues func(a, b interface{}) bool, mergeOverlapping bool,
+) int {
+	removed := 0
+	current := t.GetMin()
+	if current == nil {
+		return 0
+	}
+	for n := current.Next(); n != nil; {
+		a, b := current.interval.span(), n.interval.span()
+		// The union of an interval with a marker at its right endpoint cannot be
+		// represented as a half-open interval.
+		touching := !b.IsMarker() && current.interval.Adjacent(n.interval)
+		overlapping := current.interval.Overlaps(n.interval)
+		if !(touching || (mergeOverlapping && overlapping)) ||
+			!equalValues(current.Value, n.Value) {
+			current = n
+			n = n.Next()
+			continue
+		}
+		// Growing the right endpoint of current keeps it ordered between its
+		// neighbours, since n is its successor.
+		if a.Right.Less(b.Right) {
+			a.Right = b.Right
+		}
+		// The grown interval may equal the interval of n, so n must leave the end
+		// index before current is indexed again.
+		t.unindexEnd(current)
+		n = t.DeleteAndAscend(n)
+		current.interval = a
+		t.indexEnd(current)
+		current.flush()
+		t.updateAncestors(current)
+		removed++
+	}
+	return removed
+}
+
 // rebalanceRed rebalances the tree for the case that n is a red node whose
-// parent is also red. Otherwise, rebalanceRed does nothing.
-func (t *T) rebalanceRed(n *Node) {
+// parent is also red. Otherwise, rebalanceRed does nothing. If the black
+// height of the tree grows because a red root is painted black, true is
+// returned.
+func (t *T) rebalanceRed(n *Node) (grown bool) {
 	if n.black() { // nothing to do
-		return
+		return false
 	}
 
 	// If n is the root node, we can safely paint it black.
 	parent := n.parent
 	if parent == nil {
 		n.red = false
-		return
+		return true
 	}
 
 	// Nothing to do if parent is not red.
 	if !parent.red {
-		return
+		return false
 	}
 
 	// Actually rebalance
@@ -478,8 +844,7 @@ func (t *T) rebalanceRed(n *Node) {
 		auncle.red = false
 		parent.red = false
 		grandparent.red = true
-		t.rebalanceRed(grandparent)
-		return
+		return t.rebalanceRed(grandparent)
 	}
 	// We have a black auncle, so we rotate such that the grandparent becomes
 	// n's red sibling, and n's parent becomes black.
@@ -500,6 +865,7 @@ func (t *T) rebalanceRed(n *Node) {
 	} else {
 		t.rotateLeft(grandparent)
 	}
+	return false
 }
 
 // rebalanceBlack rebalances the tree after a black node with a black parent
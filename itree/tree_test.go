@@ -361,3 +361,356 @@ func TestRandom(t *testing.T) {
 		}
 	}
 }
+
+// TestNextBoundary tests the NextStart, NextEnd and NextBoundary functions.
+func TestNextBoundary(t *testing.T) {
+	var tree T
+	if b, starting, ending := tree.NextBoundary(Int(0)); b != nil ||
+		starting != nil || ending != nil {
+		t.Error("non-nil NextBoundary in empty tree")
+	}
+	tree.ReplaceOrInsert(Interval{Int(0), Int(10)}, 0)
+	tree.ReplaceOrInsert(Interval{Int(2), Int(4)}, 1)
+	tree.ReplaceOrInsert(Interval{Int(4), Int(6)}, 2)
+	tree.ReplaceOrInsert(Interval{Int(4), Int(10)}, 3)
+	testCases := []struct {
+		p                Point
+		b                Point
+		starting, ending []interface{}
+	}{
+		{Int(-1), Int(0), []interface{}{0}, nil},
+		{Int(1), Int(2), []interface{}{1}, nil},
+		{Int(3), Int(4), []interface{}{2, 3}, []interface{}{1}},
+		{Int(5), Int(6), nil, []interface{}{2}},
+		{Int(7), Int(10), nil, []interface{}{0, 3}},
+		{Int(11), nil, nil, nil},
+	}
+	for _, tc := range testCases {
+		b, starting, ending := tree.NextBoundary(tc.p)
+		if (b == nil) != (tc.b == nil) || (b != nil && !equal(b, tc.b)) {
+			t.Errorf("NextBoundary(%v): expected %v, got %v", tc.p, tc.b, b)
+		}
+		checkValues(t, "starting", starting, tc.starting...)
+		checkValues(t, "ending", ending, tc.ending...)
+	}
+}
+
+// TestNextBoundaryRandom compares NextStart and NextEnd on a random tree with
+// a linear search, first without and then with the end index.
+func TestNextBoundaryRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	for i := 0; i != 1000; i++ {
+		tree.ReplaceOrInsert(randomInterval(), i)
+	}
+	for i := 0; i != 200; i++ {
+		if i == 100 {
+			if tree.ends != nil {
+				t.Error("NextEnd built the end index")
+			}
+			tree.SetEndIndex(true)
+		}
+		p := Float64(2 * rand.Float64())
+		var start, end Point
+		for n := tree.GetMin(); n != nil; n = n.Next() {
+			iv := n.Interval()
+			if lessOrEqual(p, iv.Left) && (start == nil || iv.Left.Less(start)) {
+				start = iv.Left
+			}
+			if lessOrEqual(p, iv.Right) && (end == nil || iv.Right.Less(end)) {
+				end = iv.Right
+			}
+		}
+		s, starting := tree.NextStart(p)
+		if s != start {
+			t.Errorf("NextStart(%v): expected %v, got %v", p, start, s)
+		}
+		for _, n := range starting {
+			if !equal(n.Interval().Left, start) {
+				t.Errorf("NextStart(%v) returned node %v", p, n.Interval())
+			}
+		}
+		e, ending := tree.NextEnd(p)
+		if e != end {
+			t.Errorf("NextEnd(%v): expected %v, got %v", p, end, e)
+		}
+		for _, n := range ending {
+			if !equal(n.Interval().Right, end) {
+				t.Errorf("NextEnd(%v) returned node %v", p, n.Interval())
+			}
+		}
+	}
+}
+
+// TestNextEndIndex tests that NextEnd stays correct while the tree is
+// modified after the end index has been enabled.
+func TestNextEndIndex(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	tree.SetEndIndex(true)
+	check := func(what string) {
+		testInvariants(t, &tree)
+		p := Int(rand.Intn(110))
+		var end Point
+		var expected []*Node
+		for n := tree.GetMin(); n != nil; n = n.Next() {
+			r := n.Interval().Right
+			switch {
+			case r.Less(p):
+			case end == nil || r.Less(end):
+				end, expected = r, []*Node{n}
+			case equal(r, end):
+				expected = append(expected, n)
+			}
+		}
+		e, ending := tree.NextEnd(p)
+		if e != end || len(ending) != len(expected) {
+			t.Fatalf("%s: NextEnd(%v): expected %v (%d nodes), got %v (%d nodes)",
+				what, p, end, len(expected), e, len(ending))
+		}
+		for i := range ending {
+			if ending[i] != expected[i] {
+				t.Errorf("%s: NextEnd(%v): unexpected node %v", what, p,
+					ending[i].Interval())
+			}
+		}
+	}
+	random := func() Interval {
+		left := rand.Intn(100)
+		return Interval{Int(left), Int(left + 1 + rand.Intn(10))}
+	}
+	for i := 0; i != 200; i++ {
+		tree.ReplaceOrInsert(random(), i)
+		tree.ReplaceOrInsertMarker(Int(rand.Intn(100)), i)
+		check("insert")
+	}
+	for i := 0; i != 50; i++ {
+		tree.Delete(random())
+		if n := tree.Sample(rand.New(rand.NewSource(int64(i)))); n != nil {
+			tree.SetInterval(n, random())
+		}
+		check("delete and move")
+	}
+	tree.CutAt(Int(30), Int(60))
+	check("cut")
+	tree.Compact(func(a, b interface{}) bool { return true }, false)
+	check("compact")
+	tree.Compact(func(a, b interface{}) bool { return true }, true)
+	check("compact overlapping")
+	for i := 0; i != 50; i++ {
+		tree.ReplaceOrInsert(random(), i)
+	}
+	tree.DeleteOverlapping(Interval{Int(40), Int(45)})
+	check("delete overlapping")
+	tree.Shift(Int(50), Int(-5))
+	check("shift")
+	tree.SetEndIndex(true)
+	lower, upper := tree.SplitAt(Interval{Int(50), Int(51)})
+	lower.SetEndIndex(true)
+	tree = *Join(lower, upper)
+	check("split and join")
+	tree.SetEndIndex(true)
+	check("enable")
+	tree.SetEndIndex(false)
+	if tree.ends != nil {
+		t.Error("end index not disabled")
+	}
+	check("disable")
+}
+
+// TestCompactEndIndex tests that Compact keeps the end index up to date when
+// a node grows to the interval of its successor.
+func TestCompactEndIndex(t *testing.T) {
+	var tree T
+	tree.ReplaceOrInsert(Interval{Int(25), Int(31)}, 0)
+	tree.ReplaceOrInsert(Interval{Int(25), Int(32)}, 0)
+	tree.ReplaceOrInsert(Interval{Int(40), Int(41)}, 1)
+	tree.SetEndIndex(true)
+	if removed := tree.Compact(func(a, b interface{}) bool {
+		return a == b
+	}, true); removed != 1 {
+		t.Errorf("expected 1 removed node, got %d", removed)
+	}
+	testInvariants(t, &tree)
+	if e, ending := tree.NextEnd(Int(0)); e != Int(32) || len(ending) != 1 {
+		t.Errorf("expected one node ending at 32, got %v, %v", e, ending)
+	}
+}
+
+// TestQueriesRandom compares the ascending and descending queries on a random
+// tree with a linear search.
+func TestQueriesRandom(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	for i := 0; i != 1000; i++ {
+		tree.ReplaceOrInsert(randomInterval(), i)
+	}
+	descend := func(f func(func(*Node) bool)) []*Node {
+		var list []*Node
+		f(func(n *Node) bool {
+			list = append(list, n)
+			return true
+		})
+		return list
+	}
+	for i := 0; i != 100; i++ {
+		iv := randomInterval()
+		var containing, contained, overlapping, less []*Node
+		for n := tree.GetMax(); n != nil; n = n.Previous() {
+			if n.interval.ContainsInterval(iv) {
+				containing = append(containing, n)
+			}
+			if iv.ContainsInterval(n.interval) {
+				contained = append(contained, n)
+			}
+			if iv.Overlaps(n.interval) {
+				overlapping = append(overlapping, n)
+			}
+			if !iv.Less(n.interval) {
+				less = append(less, n)
+			}
+		}
+		checkNodes(t, "NodesContainingInterval",
+			tree.NodesContainingInterval(iv), reversed(containing))
+		checkNodes(t, "NodesContainedInInterval",
+			tree.NodesContainedInInterval(iv), reversed(contained))
+		checkNodes(t, "NodesOverlappingInterval",
+			tree.NodesOverlappingInterval(iv), reversed(overlapping))
+		checkNodes(t, "DescendContainingInterval",
+			descend(func(visit func(*Node) bool) {
+				tree.DescendContainingInterval(iv, visit)
+			}), containing)
+		checkNodes(t, "DescendContainedInInterval",
+			descend(func(visit func(*Node) bool) {
+				tree.DescendContainedInInterval(iv, visit)
+			}), contained)
+		checkNodes(t, "DescendOverlappingInterval",
+			descend(func(visit func(*Node) bool) {
+				tree.DescendOverlappingInterval(iv, visit)
+			}), overlapping)
+		checkNodes(t, "Descend",
+			descend(func(visit func(*Node) bool) {
+				tree.Descend(iv, visit)
+			}), less)
+		var point []*Node
+		for n := tree.GetMax(); n != nil; n = n.Previous() {
+			if n.interval.ContainsPoint(iv.Left) {
+				point = append(point, n)
+			}
+		}
+		checkNodes(t, "NodesContainingPoint",
+			tree.NodesContainingPoint(iv.Left), reversed(point))
+		checkNodes(t, "DescendContainingPoint",
+			descend(func(visit func(*Node) bool) {
+				tree.DescendContainingPoint(iv.Left, visit)
+			}), point)
+	}
+}
+
+// TestDescendStop tests stopping a descending traversal early.
+func TestDescendStop(t *testing.T) {
+	var tree T
+	for i := 0; i != 10; i++ {
+		tree.ReplaceOrInsert(Interval{Int(i), Int(i + 10)}, i)
+	}
+	var list []*Node
+	tree.DescendOverlappingInterval(Interval{Int(5), Int(8)},
+		func(n *Node) bool {
+			list = append(list, n)
+			return len(list) < 3
+		})
+	checkValues(t, "DescendOverlappingInterval", list, 7, 6, 5)
+	list = list[:0]
+	tree.Descend(Interval{Int(3), Int(20)}, func(n *Node) bool {
+		list = append(list, n)
+		return len(list) < 2
+	})
+	checkValues(t, "Descend", list, 3, 2)
+}
+
+// TestCompact tests merging touching and overlapping nodes with equal values.
+func TestCompact(t *testing.T) {
+	equalValues := func(a, b interface{}) bool {
+		return a == b
+	}
+	build := func() *T {
+		tree := &T{}
+		for _, e := range []struct {
+			left, right int
+			value       string
+		}{
+			{0, 5, "a"}, {5, 9, "a"}, {9, 12, "a"}, {11, 14, "a"}, {14, 15, "b"},
+			{15, 20, "a"}, {16, 18, "a"},
+		} {
+			tree.ReplaceOrInsert(Interval{Int(e.left), Int(e.right)}, e.value)
+		}
+		return tree
+	}
+	tree := build()
+	if removed := tree.Compact(equalValues, false); removed != 2 {
+		t.Errorf("expected 2 removed nodes, got %d", removed)
+	}
+	testContents(t, tree, map[Interval]interface{}{
+		{Int(0), Int(12)}:  "a",
+		{Int(11), Int(14)}: "a",
+		{Int(14), Int(15)}: "b",
+		{Int(15), Int(20)}: "a",
+		{Int(16), Int(18)}: "a",
+	})
+	tree = build()
+	if removed := tree.Compact(equalValues, true); removed != 4 {
+		t.Errorf("expected 4 removed nodes, got %d", removed)
+	}
+	testContents(t, tree, map[Interval]interface{}{
+		{Int(0), Int(14)}:  "a",
+		{Int(14), Int(15)}: "b",
+		{Int(15), Int(20)}: "a",
+	})
+	var empty T
+	if removed := empty.Compact(equalValues, true); removed != 0 {
+		t.Errorf("removed %d nodes from empty tree", removed)
+	}
+}
+
+// TestSetInterval tests changing the interval of nodes in a random tree.
+func TestSetInterval(t *testing.T) {
+	seedOnce.Do(seedRand)
+	tree := NewWithMonoid(listMonoid{})
+	checkMap := make(map[Interval]interface{})
+	for i := 0; i != 500; i++ {
+		iv := randomInterval()
+		tree.ReplaceOrInsert(iv, i)
+		checkMap[iv] = i
+	}
+	rng := rand.New(rand.NewSource(rand.Int63()))
+	for i := 0; i != 500; i++ {
+		n := tree.Sample(rng)
+		old := n.Interval()
+		iv := randomInterval()
+		if i%2 == 0 {
+			// small move, usually keeping the position in the sort-order
+			iv = Interval{old.Left, old.Right.(Float64) + Float64(rand.Float64()/1e6)}
+		}
+		if err := tree.SetInterval(n, iv); err != nil {
+			t.Fatalf("SetInterval(%v): %v", iv, err)
+		}
+		if tree.GetNode(iv) != n {
+			t.Errorf("node not found under new interval %v", iv)
+		}
+		delete(checkMap, old)
+		checkMap[iv] = n.Value
+	}
+	testContents(t, tree, checkMap)
+	testAggregates(t, tree)
+	min, max := tree.GetMin(), tree.GetMax()
+	if err := tree.SetInterval(min, max.Interval()); err != ErrIntervalExists {
+		t.Errorf("expected ErrIntervalExists, got %v", err)
+	}
+	if err := tree.SetInterval(min, min.Interval()); err != nil {
+		t.Errorf("setting unchanged interval: %v", err)
+	}
+	testContents(t, tree, checkMap)
+	expectPanic(t, "SetInterval empty interval", func() {
+		tree.SetInterval(min, Interval{Int(0), Int(0)})
+	})
+}
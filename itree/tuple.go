@@ -0,0 +1,112 @@
+package itree
+
+// Tuple implements Point for composite keys, e. g., (chromosome, position) or
+// (tenant, timestamp). Tuples are compared lexicographically: the first
+// differing elements decide, and a tuple which is a proper prefix of another
+// tuple is less than that tuple. Elements at the same position must be of the
+// same point type. Use Reverse for elements to be ordered descendingly.
+//
+// Like Bytes, a Tuple refers to its elements, which must not be modified while
+// the tuple is in use by a tree.
+type Tuple []Point
+
+// tupleEnd is a tuple element greater than any other element. It is used to
+// build tuples following all tuples with a given prefix.
+type tupleEnd struct{}
+
+// Less implements Point. A tupleEnd is never less than another element.
+func (tupleEnd) Less(Point) bool {
+	return false
+}
+
+// String returns a symbol for the end of a tuple prefix.
+func (tupleEnd) String() string {
+	return "⊤"
+}
+
+// compareElements compares the given tuple elements, taking tupleEnd into
+// account.
+func compareElements(x, y Point) int {
+	_, xEnd := x.(tupleEnd)
+	_, yEnd := y.(tupleEnd)
+	switch {
+	case xEnd && yEnd:
+		return 0
+	case xEnd:
+		return 1
+	case yEnd:
+		return -1
+	}
+	return compare(x, y)
+}
+
+// Less checks whether x < y. It panics if y is not Tuple.
+func (x Tuple) Less(y Point) bool {
+	return x.Compare(y) < 0
+}
+
+// Compare implements Comparer. It panics if y is not Tuple.
+func (x Tuple) Compare(y Point) int {
+	yt := y.(Tuple)
+	for i := 0; i != len(x) && i != len(yt); i++ {
+		if c := compareElements(x[i], yt[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(x) < len(yt):
+		return -1
+	case len(x) > len(yt):
+		return 1
+	}
+	return 0
+}
+
+// TuplePrefixInterval returns the interval containing exactly the tuples
+// starting with the given prefix, including the prefix itself. For an empty
+// prefix, the interval contains all tuples.
+func TuplePrefixInterval(prefix ...Point) Interval {
+	end := make(Tuple, len(prefix)+1)
+	copy(end, prefix)
+	end[len(prefix)] = tupleEnd{}
+	return Interval{
+		Left:  append(Tuple(nil), prefix...),
+		Right: end,
+	}
+}
+
+// TupleRange returns the interval containing exactly the tuples starting with
+// the given prefix followed by an element in [lo,hi), e. g., all events of a
+// tenant within a time range.
+func TupleRange(prefix Tuple, lo, hi Point) Interval {
+	return Interval{
+		Left:  append(prefix[:len(prefix):len(prefix)], lo),
+		Right: append(prefix[:len(prefix):len(prefix)], hi),
+	}
+}
+
+// Reversed implements Point by reversing the order of another point.
+type Reversed struct {
+	Point
+}
+
+// Reverse returns the given point with its order reversed.
+func Reverse(p Point) Reversed {
+	return Reversed{p}
+}
+
+// Less checks whether x > y with respect to the underlying points. It panics if
+// y is not Reversed.
+func (x Reversed) Less(y Point) bool {
+	return y.(Reversed).Point.Less(x.Point)
+}
+
+// Compare implements Comparer. It panics if y is not Reversed.
+func (x Reversed) Compare(y Point) int {
+	return compare(y.(Reversed).Point, x.Point)
+}
+
+// Point interface checks for composite points.
+var _ = []Comparer{
+	Tuple(nil), Reversed{},
+}
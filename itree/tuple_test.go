@@ -0,0 +1,112 @@
+package itree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestTupleOrder tests the lexicographic order of tuples.
+func TestTupleOrder(t *testing.T) {
+	ordered := []Tuple{
+		{},
+		{Int(1)},
+		{Int(1), String("a")},
+		{Int(1), String("a"), Int(0)},
+		{Int(1), String("b")},
+		{Int(2)},
+		{Int(2), tupleEnd{}},
+		{Int(3), String("")},
+	}
+	for i, x := range ordered {
+		for j, y := range ordered {
+			if c, expected := x.Compare(y), sign(i-j); c != expected {
+				t.Errorf("%v vs. %v: expected %d, got %d", x, y, expected, c)
+			}
+			if x.Less(y) != (i < j) {
+				t.Errorf("%v < %v: unexpected result", x, y)
+			}
+		}
+	}
+}
+
+// sign returns the sign of the given integer.
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+// TestReverse tests reversed points.
+func TestReverse(t *testing.T) {
+	if !Reverse(Int(2)).Less(Reverse(Int(1))) ||
+		Reverse(Int(1)).Less(Reverse(Int(2))) ||
+		Reverse(Int(1)).Compare(Reverse(Int(1))) != 0 {
+		t.Error("unexpected order")
+	}
+	// Tenants ascending, timestamps descending.
+	a := Tuple{String("a"), Reverse(Int(10))}
+	b := Tuple{String("a"), Reverse(Int(5))}
+	if !a.Less(b) {
+		t.Errorf("expected %v < %v", a, b)
+	}
+}
+
+// TestTuplePrefix tests tuple prefix intervals in a tree against brute force.
+func TestTuplePrefix(t *testing.T) {
+	seedOnce.Do(seedRand)
+	var tree T
+	var keys []Tuple
+	for i := 0; i != 200; i++ {
+		start := Tuple{Int(rand.Intn(5)), Int(rand.Intn(100))}
+		if _, present := tree.ReplaceOrInsert(
+			Interval{start, Tuple{start[0], start[1].(Int) + 1}}, nil,
+		); !present {
+			keys = append(keys, start)
+		}
+	}
+	testInvariants(t, &tree)
+	for chrom := -1; chrom <= 5; chrom++ {
+		prefix := TuplePrefixInterval(Int(chrom))
+		var expected int
+		for _, key := range keys {
+			if key[0] == Int(chrom) {
+				expected++
+				if !prefix.ContainsPoint(key) {
+					t.Errorf("%v does not contain %v", prefix, key)
+				}
+			}
+		}
+		if n := len(tree.NodesContainedInInterval(prefix)); n != expected {
+			t.Errorf("%v: expected %d intervals, got %d", prefix, expected, n)
+		}
+		rng := TupleRange(Tuple{Int(chrom)}, Int(20), Int(40))
+		expected = 0
+		for _, key := range keys {
+			if key[0] == Int(chrom) && key[1].(Int) >= 20 && key[1].(Int) < 40 {
+				expected++
+			}
+		}
+		if n := len(tree.NodesContainedInInterval(rng)); n != expected {
+			t.Errorf("%v: expected %d intervals, got %d", rng, expected, n)
+		}
+	}
+	all := TuplePrefixInterval()
+	if n := len(tree.NodesContainedInInterval(all)); n != len(keys) {
+		t.Errorf("expected %d intervals, got %d", len(keys), n)
+	}
+}
+
+// TestTuplePrefixAliasing tests that prefix helpers do not modify the prefix.
+func TestTuplePrefixAliasing(t *testing.T) {
+	prefix := make(Tuple, 1, 4)
+	prefix[0] = Int(1)
+	lo := TupleRange(prefix, Int(0), Int(5))
+	TuplePrefixInterval(prefix...)
+	if lo.Left.(Tuple)[1] != Int(0) || lo.Right.(Tuple)[1] != Int(5) {
+		t.Errorf("unexpected range %v", lo)
+	}
+}